@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// adoptCmd represents the adopt command
+var adoptCmd = &cobra.Command{
+	Use:   "adopt",
+	Short: "Imports the variants of the config file as environments",
+	Long: `Finds the files next to the config file that hold other versions of it
+	(e.g. .env.local, .env.staging) and creates an environment for each one,
+	named after its suffix.
+	Usage:
+
+	jorge adopt
+	jorge adopt --pattern ".env.*" --gitignore
+	jorge adopt --delete`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		pattern, _ := cmd.Flags().GetString("pattern")
+		deleteOriginals, _ := cmd.Flags().GetBool("delete")
+		ignoreOriginals, _ := cmd.Flags().GetBool("gitignore")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		if deleteOriginals && ignoreOriginals {
+			exitWithMessage("adopt", "Use either --delete or --gitignore")
+		}
+
+		adopted, err := jorge.Adopt(pattern, deleteOriginals, ignoreOriginals)
+
+		if err != nil {
+			exitWithError("adopt", err)
+		}
+
+		printResult(result{Operation: "adopt", Data: adopted}, func() {
+			for _, variant := range adopted.Adopted {
+				fmt.Printf("Adopted %s as %s\n", variant.Path, variant.EnvName)
+			}
+
+			for _, variant := range adopted.Collisions {
+				fmt.Fprintf(os.Stderr, "Skipped %s, the environment %s already exists\n", variant.Path, variant.EnvName)
+			}
+
+			if len(adopted.Adopted) == 0 && len(adopted.Collisions) == 0 {
+				fmt.Println("No variants of the config file found")
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(adoptCmd)
+	adoptCmd.Flags().StringP("pattern", "p", "", "Glob pattern of the variants, relative to the directory of the config file")
+	adoptCmd.Flags().Bool("delete", false, "Delete the adopted files")
+	adoptCmd.Flags().Bool("gitignore", false, "Add the adopted files to .gitignore")
+}
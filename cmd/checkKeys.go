@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// checkKeysCmd represents the check-keys command
+var checkKeysCmd = &cobra.Command{
+	Use:   "check-keys",
+	Short: "Finds the keys that some environments lack",
+	Long: `Compares the keys of every environment and prints the keys that are
+	missing from at least one of them. With --fix-from the missing keys are
+	copied from the given environment to the others.
+	Usage:
+
+	jorge check-keys
+	jorge check-keys --fix-from default
+	jorge check-keys --fix-from default --placeholder CHANGE_ME`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		fixFrom, _ := cmd.Flags().GetString("fix-from")
+		placeholder, _ := cmd.Flags().GetString("placeholder")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		var matrix jorge.KeysMatrix
+		var fixed []jorge.KeyReport
+		var err *jorge.EncapsulatedError
+
+		if len(fixFrom) > 0 {
+			fixed, err = jorge.FixMissingKeys(fixFrom, placeholder, cmd.Flags().Changed("placeholder"))
+		}
+
+		if err == nil {
+			matrix, err = jorge.CheckKeys()
+		}
+
+		if err != nil {
+			exitWithError("check-keys", err)
+		}
+
+		printResult(result{Operation: "check-keys", Data: map[string]interface{}{"fixed": fixed, "missing": matrix}}, func() {
+			printKeysMatrix(matrix, fixed)
+		})
+
+		if len(matrix.Keys) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// printKeysMatrix
+// Prints the keys that were copied and a table with the environments that
+// lack each key
+func printKeysMatrix(matrix jorge.KeysMatrix, fixed []jorge.KeyReport) {
+	for _, report := range fixed {
+		fmt.Printf("Added %s (%s) to %s\n", report.Key, report.File, strings.Join(report.Missing, ", "))
+	}
+
+	if len(fixed) > 0 {
+		fmt.Println("The working configuration file was not changed. Run `jorge restore` to get the new keys of the current environment")
+	}
+
+	if len(matrix.Keys) == 0 {
+		fmt.Println("Every environment has the same keys")
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "FILE\tKEY\t%s\n", strings.Join(matrix.Envs, "\t"))
+	for _, report := range matrix.Keys {
+		cells := []string{}
+		for _, env := range matrix.Envs {
+			if jorge.Contains(report.Missing, env) {
+				cells = append(cells, "missing")
+			} else {
+				cells = append(cells, "ok")
+			}
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", report.File, report.Key, strings.Join(cells, "\t"))
+	}
+	writer.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(checkKeysCmd)
+	checkKeysCmd.Flags().String("fix-from", "", "Copy the missing keys from this environment to the others")
+	checkKeysCmd.Flags().String("placeholder", "", "Value of the copied keys, instead of the value of the source environment")
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/dpliakos/jorge/internal/jorge"
 	log "github.com/sirupsen/logrus"
@@ -16,32 +15,29 @@ var commitCmd = &cobra.Command{
 	Long:  `Updates the environment configuration with the current version of the configuration file`,
 	Run: func(cmd *cobra.Command, args []string) {
 		debug, _ := cmd.Flags().GetBool("debug")
+		noHooks, _ := cmd.Flags().GetBool("no-hooks")
+		noValidate, _ := cmd.Flags().GetBool("no-validate")
 
 		if debug {
 			log.SetLevel(log.DebugLevel)
 		}
 
-		err := jorge.CommitCurrentEnv()
+		jorge.SetHooksEnabled(!noHooks)
+		jorge.SetValidationEnabled(!noValidate)
 
-		if err != nil {
-			if debug && err.OriginalErr != nil {
-				fmt.Fprintf(os.Stderr, "%s\n", err.OriginalErr.Error())
-			}
-
-			fmt.Fprintf(os.Stderr, "%s\n", err.Message)
-			fmt.Fprintf(os.Stderr, "%s\n", err.Solution)
+		if err := jorge.CommitCurrentEnv(); err != nil {
+			exitWithError("commit", err)
+		}
 
-			if err.Code > 0 {
-				os.Exit(err.Code)
-			} else {
-				os.Exit(1)
-			}
-		} else {
+		currentEnv, _ := jorge.GetCurrentEnv()
+		printResult(result{Operation: "commit", Env: currentEnv}, func() {
 			fmt.Println("Env committed")
-		}
+		})
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(commitCmd)
+	commitCmd.Flags().Bool("no-hooks", false, "Skip the hooks configured in .jorge/config.yml")
+	commitCmd.Flags().Bool("no-validate", false, "Skip the validation against .jorge/schema.yml")
 }
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// describeCmd represents the describe command
+var describeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "Shows or sets the description of an environment",
+	Long: `Sets the description and the expiry date of an environment. When no
+	description is given, the metadata of the environment is shown.
+	Usage:
+
+	jorge describe <env_name>
+	jorge describe <env_name> "description"
+	jorge describe <env_name> --expires 2022-12-31`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		expires, _ := cmd.Flags().GetString("expires")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		if len(args) == 0 {
+			exitWithMessage("describe", "No environment specified")
+		}
+		selectedEnv := args[0]
+
+		var err *jorge.EncapsulatedError
+		if len(args) > 1 {
+			err = jorge.DescribeEnv(selectedEnv, strings.Join(args[1:], " "))
+		}
+
+		if err == nil && cmd.Flags().Changed("expires") {
+			err = jorge.SetEnvExpiry(selectedEnv, expires)
+		}
+
+		var meta jorge.EnvMeta
+		if err == nil {
+			meta, err = jorge.GetEnvMeta(selectedEnv)
+		}
+
+		if err != nil {
+			exitWithError("describe", err)
+		}
+
+		printResult(result{Operation: "describe", Env: selectedEnv, Data: meta}, func() {
+			printEnvMeta(selectedEnv, meta)
+		})
+	},
+}
+
+// printEnvMeta
+// Prints the metadata of an environment
+func printEnvMeta(selectedEnv string, meta jorge.EnvMeta) {
+	fmt.Printf("Environment: %s\n", selectedEnv)
+	fmt.Printf("Description: %s\n", meta.Description)
+	fmt.Printf("Tags:        %s\n", strings.Join(meta.Tags, ", "))
+	fmt.Printf("Creator:     %s\n", meta.Creator)
+
+	if len(meta.Include) > 0 {
+		fmt.Printf("Includes:    %s\n", strings.Join(meta.Include, ", "))
+	}
+
+	if !meta.Created.IsZero() {
+		fmt.Printf("Created:     %s\n", meta.Created.Local().Format("2006-01-02 15:04"))
+	}
+
+	if !meta.Updated.IsZero() {
+		fmt.Printf("Updated:     %s\n", meta.Updated.Local().Format("2006-01-02 15:04"))
+	}
+
+	if meta.Revision > 0 {
+		fmt.Printf("Revision:    %d\n", meta.Revision)
+	}
+
+	if meta.IsExpired() {
+		fmt.Printf("Expires:     %s (expired)\n", meta.Expires.Format("2006-01-02"))
+	} else if !meta.Expires.IsZero() {
+		fmt.Printf("Expires:     %s\n", meta.Expires.Format("2006-01-02"))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+	describeCmd.Flags().StringP("expires", "e", "", "Set the expiry date (YYYY-MM-DD) of the environment or `never` to remove it")
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:     "doctor",
+	Aliases: []string{"fsck"},
+	Short:   "Checks the .jorge directory for problems",
+	Long: `Checks that the jorge configuration is valid, that the environments
+	contain the configuration file, that the permissions are private and that
+	.jorge is ignored by git. Use --fix to repair what can be safely repaired.
+	Usage:
+
+	jorge doctor
+	jorge doctor --fix`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		findings, err := jorge.Doctor(fix)
+
+		if err != nil {
+			exitWithError("doctor", err)
+		}
+
+		unresolved := 0
+		fixable := 0
+		for _, finding := range findings {
+			if !finding.Fixed {
+				unresolved++
+			}
+
+			if finding.Fixable && !finding.Fixed {
+				fixable++
+			}
+		}
+
+		printResult(result{Operation: "doctor", Data: findings}, func() {
+			printFindings(findings, fixable)
+		})
+
+		if unresolved > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// printFindings
+// Prints the problems that were found and whether they were fixed
+func printFindings(findings []jorge.DoctorFinding, fixable int) {
+	if len(findings) == 0 {
+		fmt.Println("No problems found")
+		return
+	}
+
+	for _, finding := range findings {
+		status := "problem"
+		if finding.Fixed {
+			status = "fixed"
+		} else if finding.Fixable {
+			status = "fixable"
+		}
+
+		fmt.Printf("[%s] %s: %s\n", status, finding.Check, finding.Problem)
+	}
+
+	if fixable > 0 {
+		fmt.Println("Run `jorge doctor --fix` to repair the fixable problems")
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().Bool("fix", false, "Repair the problems that can be safely repaired")
+}
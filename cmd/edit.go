@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// editCmd represents the edit command
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edits an environment without using it",
+	Long: `Opens the stored configuration file of an environment in $VISUAL or
+	$EDITOR. The edited file is checked against the schema and stored without
+	changing the current environment.
+	Usage:
+
+	jorge edit <env_name>
+	jorge edit <env_name> <file_name>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		noValidate, _ := cmd.Flags().GetBool("no-validate")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		jorge.SetValidationEnabled(!noValidate)
+
+		if len(args) < 1 {
+			exitWithMessage("edit", "Usage: jorge edit <env_name> [file_name]")
+		}
+
+		var fileName string
+		if len(args) > 1 {
+			fileName = args[1]
+		}
+
+		changed, err := jorge.EditEnv(args[0], fileName, os.Stdin)
+
+		if err != nil {
+			exitWithError("edit", err)
+		}
+
+		printResult(result{Operation: "edit", Env: args[0], Data: map[string]bool{"changed": changed}}, func() {
+			if changed {
+				fmt.Printf("Stored the changes of %s\n", args[0])
+			} else {
+				fmt.Println("No changes")
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+	editCmd.Flags().Bool("no-validate", false, "Skip the validation against .jorge/schema.yml")
+}
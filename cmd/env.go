@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// envCmd represents the env command
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Prints shell statements that export an environment",
+	Long: `Prints the statements that export the KEY=VALUE entries of an environment,
+	for scripts that can not use jorge exec. The shell is detected from $SHELL
+	unless it is given. Without an environment name the current environment is
+	exported.
+	Usage:
+
+	eval "$(jorge env [env_name])"
+	eval "$(jorge env [env_name] --unset)"
+	jorge env [env_name] --shell ` + strings.Join(jorge.Shells, "|") + ` | source`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		shell, _ := cmd.Flags().GetString("shell")
+		unset, _ := cmd.Flags().GetBool("unset")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		selectedEnv := ""
+		if len(args) > 0 {
+			selectedEnv = args[0]
+		}
+
+		data, err := jorge.ShellEnv(selectedEnv, shell, unset)
+
+		if err != nil {
+			exitWithError("env", err)
+		}
+
+		printResult(result{Operation: "env", Env: selectedEnv, Data: map[string]string{"script": string(data)}}, func() {
+			os.Stdout.Write(data)
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+	envCmd.Flags().String("shell", "", "Shell syntax: "+strings.Join(jorge.Shells, ", ")+" (detected from $SHELL by default)")
+	envCmd.Flags().Bool("unset", false, "Print the statements that remove the variables instead")
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec",
+	Short: "Runs a command with the variables of an environment",
+	Long: `Runs a command with the KEY=VALUE entries of an environment exported as
+	environment variables. Secrets and shared environments are resolved, but
+	nothing is written to the configuration file.
+	Usage:
+
+	jorge exec -- <command> [args...]
+	jorge exec <env_name> -- <command> [args...]`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		var selectedEnv string
+		command := args
+
+		// Flags are not parsed after the first argument, so that they are
+		// passed to the command. A -- that follows the environment name is
+		// therefore part of the arguments
+		if len(args) >= 2 && args[1] == "--" {
+			selectedEnv, command = args[0], args[2:]
+		}
+
+		exitCode, err := jorge.Exec(selectedEnv, command)
+
+		if err != nil {
+			exitWithError("exec", err)
+		}
+
+		// The output of the command is written to stderr in json mode
+		printResult(result{Operation: "exec", Env: selectedEnv, Data: map[string]int{"exitCode": exitCode}}, nil)
+		os.Exit(exitCode)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+	execCmd.Flags().SetInterspersed(false)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// globalCmd represents the global command
+var globalCmd = &cobra.Command{
+	Use:   "global",
+	Short: "Manages the shared environments of the user",
+	Long: `Manages the configuration fragments that are shared between projects.
+	They are kept under $XDG_DATA_HOME/jorge/shared (~/.local/share/jorge/shared
+	by default) and are included by environments with jorge include.
+	Usage:
+
+	jorge global ls
+	jorge global add <name> <file>
+	jorge global rm <name>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		if len(args) < 1 {
+			exitWithMessage("global", "Usage: jorge global ls|add|rm")
+		}
+
+		var err *jorge.EncapsulatedError
+		operation := "global " + args[0]
+		switch action := args[0]; {
+		case action == "ls":
+			var sharedEnvs []jorge.SharedEnv
+			if sharedEnvs, err = jorge.ListSharedEnvs(); err == nil {
+				printResult(result{Operation: operation, Data: sharedEnvs}, func() {
+					writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					for _, sharedEnv := range sharedEnvs {
+						fmt.Fprintf(writer, "shared/%s\t%d B\t%s\n", sharedEnv.Name, sharedEnv.Size, sharedEnv.Modified.Local().Format("2006-01-02 15:04"))
+					}
+					writer.Flush()
+				})
+			}
+		case action == "add" && len(args) == 3:
+			if err = jorge.AddSharedEnv(args[1], args[2]); err == nil {
+				printResult(result{Operation: operation, Env: "shared/" + args[1]}, func() {
+					fmt.Printf("Stored %s as shared/%s\n", args[2], args[1])
+				})
+			}
+		case action == "rm" && len(args) == 2:
+			if err = jorge.RemoveSharedEnv(args[1]); err == nil {
+				printResult(result{Operation: operation, Env: "shared/" + args[1]}, func() {
+					fmt.Printf("Removed shared/%s\n", args[1])
+				})
+			}
+		default:
+			exitWithMessage("global", "Usage: jorge global ls | add <name> <file> | rm <name>")
+		}
+
+		if err != nil {
+			exitWithError(operation, err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(globalCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// includeCmd represents the include command
+var includeCmd = &cobra.Command{
+	Use:   "include",
+	Short: "Adds or removes a shared environment of an environment",
+	Long: `Makes an environment include a shared environment of the global
+	store. The shared keys are written to the configuration file when the
+	environment is used and are left out when it is committed.
+	Usage:
+
+	jorge include <env_name> add shared/<name>
+	jorge include <env_name> rm shared/<name>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		if len(args) < 3 {
+			exitWithMessage("include", "Usage: jorge include <env_name> add|rm shared/<name>")
+		}
+
+		selectedEnv, action, ref := args[0], args[1], args[2]
+
+		var err *jorge.EncapsulatedError
+		switch action {
+		case "add":
+			err = jorge.AddEnvInclude(selectedEnv, ref)
+		case "rm":
+			err = jorge.RemoveEnvInclude(selectedEnv, ref)
+		default:
+			exitWithMessage("include", fmt.Sprintf("Unknown action %s. Use add or rm", action))
+		}
+
+		if err != nil {
+			exitWithError("include "+action, err)
+		}
+
+		printResult(result{Operation: "include " + action, Env: selectedEnv, Data: map[string]string{"include": ref}}, func() {
+			if action == "add" {
+				fmt.Printf("%s now includes %s. Run `jorge use %s` to apply it\n", selectedEnv, ref, selectedEnv)
+			} else {
+				fmt.Printf("%s no longer includes %s\n", selectedEnv, ref)
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(includeCmd)
+}
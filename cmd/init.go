@@ -13,36 +13,70 @@ import (
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initializes a jorge environment",
+	Long: `Initializes a jorge project in the current directory. When no config
+	file is declared, jorge offers the configuration files it finds in the
+	project. Variants of the config files (e.g. .env.staging) are imported as
+	environments of their own.
+	Usage:
+
+	jorge init
+	jorge init --config .env --config config/app.toml
+	jorge init --config .env --yes
+	jorge init --name services/api --config services/api/.env`,
 	Run: func(cmd *cobra.Command, args []string) {
 		debug, _ := cmd.Flags().GetBool("debug")
-		configFilePath, _ := cmd.Flags().GetString("config")
+		configFilePaths, _ := cmd.Flags().GetStringArray("config")
+		yes, _ := cmd.Flags().GetBool("yes")
+		name, _ := cmd.Flags().GetString("name")
 
 		if debug {
 			log.SetLevel(log.DebugLevel)
 		}
 
-		err := jorge.Init(configFilePath)
+		// Results that are read by scripts are never interrupted by a prompt
+		interactive := !yes && !isJSONOutput() && isTerminal(os.Stdin)
+
+		var importedEnvs []string
+		var err *jorge.EncapsulatedError
+		if len(name) > 0 {
+			importedEnvs, err = jorge.InitNamedProject(name, configFilePaths, interactive)
+		} else {
+			importedEnvs, err = jorge.Init(configFilePaths, interactive)
+		}
 
 		if err != nil {
-			if debug && err.OriginalErr != nil {
-				fmt.Fprintf(os.Stderr, "%s\n", err.OriginalErr.Error())
-			}
+			exitWithError("init", err)
+		}
 
-			fmt.Fprintf(os.Stderr, "%s\n", err.Message)
-			fmt.Fprintf(os.Stderr, "%s\n", err.Solution)
+		printResult(result{Operation: "init", Env: "default", Data: map[string][]string{"importedEnvs": importedEnvs}}, func() {
+			fmt.Println("Created new jorge project")
 
-			if err.Code > 0 {
-				os.Exit(err.Code)
-			} else {
-				os.Exit(1)
+			for _, env := range importedEnvs {
+				fmt.Printf("Imported environment %s\n", env)
 			}
-		} else {
-			fmt.Println("Created new jorge project")
-		}
+		})
 	},
 }
 
+// isTerminal
+// Determines whether the file is attached to a terminal. The null device is a
+// character device as well, so it is excluded explicitly
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	if devNull, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, devNull) {
+		return false
+	}
+
+	return true
+}
+
 func init() {
 	rootCmd.AddCommand(initCmd)
-	initCmd.Flags().StringP("config", "c", "", "Declare the project's config file path")
+	initCmd.Flags().StringArrayP("config", "c", []string{}, "Declare the project's config file path. Can be repeated to track several files")
+	initCmd.Flags().BoolP("yes", "y", false, "Never prompt. Fail when the config file is not declared")
+	initCmd.Flags().String("name", "", "Create a named project of the monorepo, named by the path of its directory")
 }
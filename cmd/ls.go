@@ -3,10 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/dpliakos/jorge/internal/jorge"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 // lsCmd represents the ls command
@@ -16,31 +19,141 @@ var lsCmd = &cobra.Command{
 	Long: `Shows a list with the configuration environments that the user created.
 	Usage:
 
-	jorge ls`,
+	jorge ls
+	jorge ls -l --sort modified
+	jorge ls --tag <tag>
+	jorge ls --output json`,
+	// The environments can also be printed as yaml. table is the text output
+	Annotations: map[string]string{outputFormatsAnnotation: "table,yaml"},
 	Run: func(cmd *cobra.Command, args []string) {
 		debug, _ := cmd.Flags().GetBool("debug")
+		long, _ := cmd.Flags().GetBool("long")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+		tag, _ := cmd.Flags().GetString("tag")
+		sortKey, _ := cmd.Flags().GetString("sort")
 
 		if debug {
 			log.SetLevel(log.DebugLevel)
 		}
 
-		if err := jorge.ListEnvironments(); err != nil {
-			if debug && err.OriginalErr != nil {
-				fmt.Fprintf(os.Stderr, "%s\n", err.OriginalErr.Error())
+		envs, err := jorge.ListEnvironments(tag)
+
+		if err == nil {
+			err = jorge.SortEnvironments(envs, sortKey)
+		}
+
+		if err != nil {
+			exitWithError("ls", err)
+		}
+
+		switch {
+		case output == "yaml":
+			data, marshalErr := yaml.Marshal(envs)
+			if marshalErr != nil {
+				exitWithError("ls", &jorge.EncapsulatedError{
+					OriginalErr: marshalErr,
+					Message:     jorge.ErrorCode.Str(jorge.E143),
+					Solution:    jorge.SolutionMessage.Str(jorge.S135, "yaml", "the environments", marshalErr),
+					Code:        143,
+				})
+			}
+			fmt.Print(string(data))
+		case isJSONOutput():
+			currentEnv := ""
+			for _, env := range envs {
+				if env.Current {
+					currentEnv = env.Name
+				}
+			}
+			printResult(result{Operation: "ls", Env: currentEnv, Data: envs}, nil)
+		case quiet:
+			for _, env := range envs {
+				fmt.Println(env.Name)
 			}
+		default:
+			printEnvironments(envs, long)
+		}
+	},
+}
+
+// printEnvironments
+// Prints the environments as a table. The current environment is marked with *
+func printEnvironments(envs []jorge.EnvInfo, long bool) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer writer.Flush()
+
+	if long {
+		fmt.Fprintln(writer, "  NAME\tSIZE\tMODIFIED\tLAST COMMIT\tPARENT\tTAGS\tDESCRIPTION")
+	}
+
+	for _, env := range envs {
+		labels := []string{}
+		if !env.Committed {
+			labels = append(labels, "(uncommitted)")
+		} else if env.Dirty {
+			labels = append(labels, "(dirty)")
+		}
+		if env.Expired {
+			labels = append(labels, "(expired)")
+		}
 
-			fmt.Fprintf(os.Stderr, "%s\n", err.Message)
-			fmt.Fprintf(os.Stderr, "%s\n", err.Solution)
+		name := strings.Join(append([]string{env.Name}, labels...), " ")
 
-			if err.Code > 0 {
-				os.Exit(err.Code)
+		if !long {
+			if env.Current {
+				fmt.Fprintf(writer, "* %s\n", name)
 			} else {
-				os.Exit(1)
+				fmt.Fprintln(writer, name)
 			}
+			continue
 		}
-	},
+
+		marker := " "
+		if env.Current {
+			marker = "*"
+		}
+
+		fmt.Fprintf(writer, "%s %s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			marker,
+			name,
+			formatSize(env),
+			formatTime(env.Committed, env.Modified.Local().Format("2006-01-02 15:04")),
+			formatTime(env.Committed, env.LastCommit.Local().Format("2006-01-02 15:04")),
+			orDash(env.Meta.Parent),
+			orDash(strings.Join(env.Meta.Tags, ",")),
+			env.Meta.Description,
+		)
+	}
+}
+
+func formatSize(env jorge.EnvInfo) string {
+	if !env.Committed {
+		return "-"
+	}
+
+	return fmt.Sprintf("%dB", env.Size)
+}
+
+func formatTime(committed bool, value string) string {
+	if !committed {
+		return "-"
+	}
+
+	return value
+}
+
+func orDash(value string) string {
+	if len(value) == 0 {
+		return "-"
+	}
+
+	return value
 }
 
 func init() {
 	rootCmd.AddCommand(lsCmd)
+	lsCmd.Flags().BoolP("long", "l", false, "Show the size, the modification times and the metadata of the environments")
+	lsCmd.Flags().BoolP("quiet", "q", false, "Show only the names of the environments")
+	lsCmd.Flags().StringP("tag", "t", "", "Show only the environments labeled with the tag")
+	lsCmd.Flags().StringP("sort", "s", "name", "Sort the environments by name, modified or size")
 }
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// countingWriter
+// Counts the bytes that are written through it
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+}
+
+func (w *countingWriter) Write(data []byte) (int, error) {
+	n, err := w.writer.Write(data)
+	w.count += int64(n)
+	return n, err
+}
+
+// materializeCmd represents the materialize command
+var materializeCmd = &cobra.Command{
+	Use:   "materialize",
+	Short: "Writes an environment to another path",
+	Long: `Writes the configuration files of an environment to another location than
+	the tracked files (e.g. for CI or docker builds), without using the
+	environment. Secrets and shared environments are resolved. When the project
+	tracks several files, --to is a directory, unless --file selects one of them.
+	Usage:
+
+	jorge materialize [env_name] --to build/.env
+	jorge materialize [env_name] --to build/ --mode 0640
+	jorge materialize [env_name] --file app.json --to -`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		to, _ := cmd.Flags().GetString("to")
+		fileName, _ := cmd.Flags().GetString("file")
+		modeFlag, _ := cmd.Flags().GetString("mode")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		selectedEnv := ""
+		if len(args) > 0 {
+			selectedEnv = args[0]
+		}
+
+		mode := os.FileMode(0)
+		if len(modeFlag) > 0 {
+			var parseErr error
+			if mode, parseErr = jorge.ParseFileMode(modeFlag); parseErr != nil {
+				exitWithMessage("materialize", fmt.Sprintf("Invalid file mode %s, use an octal mode such as 0600", modeFlag))
+			}
+		}
+
+		// The contents that are written to stdout are part of the result in
+		// json mode
+		var contents bytes.Buffer
+		stdout := countingWriter{writer: os.Stdout}
+		if isJSONOutput() {
+			stdout.writer = &contents
+		}
+
+		written, err := jorge.Materialize(selectedEnv, fileName, to, mode, &stdout)
+
+		if err != nil {
+			exitWithError("materialize", err)
+		}
+
+		var nBytes int64
+		for _, path := range written {
+			if info, statErr := os.Stat(path); statErr == nil {
+				nBytes += info.Size()
+			}
+		}
+
+		data := map[string]interface{}{"files": written}
+		if to == "-" {
+			nBytes = stdout.count
+			data["contents"] = contents.String()
+		}
+
+		printResult(result{Operation: "materialize", Env: selectedEnv, BytesWritten: nBytes, Data: data}, func() {
+			if to != "-" {
+				for _, path := range written {
+					fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+				}
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(materializeCmd)
+	materializeCmd.Flags().String("to", "", "Path to write to, or - for stdout")
+	materializeCmd.Flags().String("file", "", "Write only this tracked file")
+	materializeCmd.Flags().String("mode", "", "Permissions of the written files (default the mode of the working files, or 0600)")
+	materializeCmd.MarkFlagRequired("to")
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const outputText = "text"
+const outputJSON = "json"
+
+// outputFormatsAnnotation
+// Annotation of the commands that print their results in more formats than
+// text and json. The formats are separated by commas
+const outputFormatsAnnotation = "jorge/output-formats"
+
+// output
+// The value of the global --output flag
+var output = outputText
+
+// result
+// The outcome of a command, as it is printed with --output json. Every
+// command prints the same fields, the details of a command are kept in data
+type result struct {
+	Operation    string      `json:"operation"`
+	Env          string      `json:"env,omitempty"`
+	BytesWritten int64       `json:"bytesWritten"`
+	Warnings     []string    `json:"warnings"`
+	Data         interface{} `json:"data,omitempty"`
+}
+
+// errorResult
+// The error of a command, as it is printed with --output json
+type errorResult struct {
+	Operation string       `json:"operation"`
+	Error     errorDetails `json:"error"`
+	Warnings  []string     `json:"warnings"`
+}
+
+type errorDetails struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	Solution string `json:"solution,omitempty"`
+	Cause    string `json:"cause,omitempty"`
+}
+
+// warningCollector
+// Keeps the warnings that are logged while a command runs, so that they are
+// part of its JSON result
+type warningCollector struct {
+	warnings []string
+}
+
+func (collector *warningCollector) Levels() []log.Level {
+	return []log.Level{log.WarnLevel}
+}
+
+func (collector *warningCollector) Fire(entry *log.Entry) error {
+	collector.warnings = append(collector.warnings, entry.Message)
+	return nil
+}
+
+var warnings = warningCollector{warnings: []string{}}
+
+// outputFormats
+// Returns the formats that the command can print its results in
+func outputFormats(cmd *cobra.Command) []string {
+	formats := []string{outputText, outputJSON}
+	if extra, found := cmd.Annotations[outputFormatsAnnotation]; found {
+		formats = append(formats, strings.Split(extra, ",")...)
+	}
+	return formats
+}
+
+// setupOutput
+// Checks the --output flag and prepares the output of the command. In json
+// mode the warnings are collected and the output of the hooks is moved to
+// stderr, so that stdout only holds the result
+func setupOutput(cmd *cobra.Command) {
+	if formats := outputFormats(cmd); !jorge.Contains(formats, output) {
+		fmt.Fprintf(os.Stderr, "Unknown output format %s. Please use one of: %s\n", output, strings.Join(formats, ", "))
+		os.Exit(1)
+	}
+
+	if isJSONOutput() {
+		log.AddHook(&warnings)
+		jorge.SetCommandOutput(os.Stderr)
+	}
+}
+
+// isJSONOutput
+// Determines whether the results are printed as JSON
+func isJSONOutput() bool {
+	return output == outputJSON
+}
+
+// printJSON
+// Prints a value as indented JSON to stdout
+func printJSON(value interface{}) {
+	data, _ := json.MarshalIndent(value, "", "  ")
+	fmt.Println(string(data))
+}
+
+// printResult
+// Prints the result as JSON, or calls text to print it for humans
+func printResult(res result, text func()) {
+	if !isJSONOutput() {
+		if text != nil {
+			text()
+		}
+		return
+	}
+
+	res.Warnings = warnings.warnings
+	printJSON(res)
+}
+
+// exitWithError
+// Prints the error, as JSON or as its message and solution to stderr, and
+// exits with its code
+func exitWithError(operation string, err *jorge.EncapsulatedError) {
+	debug, _ := rootCmd.PersistentFlags().GetBool("debug")
+
+	code := err.Code
+	if code <= 0 {
+		code = 1
+	}
+
+	if isJSONOutput() {
+		details := errorDetails{Code: code, Message: err.Message, Solution: err.Solution}
+		if debug && err.OriginalErr != nil {
+			details.Cause = err.OriginalErr.Error()
+		}
+
+		printJSON(errorResult{Operation: operation, Error: details, Warnings: warnings.warnings})
+		os.Exit(code)
+	}
+
+	if debug && err.OriginalErr != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.OriginalErr.Error())
+	}
+
+	fmt.Fprintf(os.Stderr, "%s\n", err.Message)
+	fmt.Fprintf(os.Stderr, "%s\n", err.Solution)
+	os.Exit(code)
+}
+
+// exitWithMessage
+// Reports a wrong invocation of the command, e.g. a missing argument, and
+// exits with 1
+func exitWithMessage(operation string, message string) {
+	if isJSONOutput() {
+		printJSON(errorResult{Operation: operation, Error: errorDetails{Code: 1, Message: message}, Warnings: warnings.warnings})
+	} else {
+		fmt.Fprintf(os.Stderr, "%s\n", message)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// profileCmd represents the profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Uses a group of environments at once",
+	Long: `A profile maps the projects of a monorepo, or tracked files, to the
+	environments they use. Profiles are defined under profiles in .jorge/config.yml.
+	When one of the environments can not be used, the projects that were already
+	switched are restored.
+	Usage:
+
+	jorge profile ls
+	jorge profile use <name>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		noHooks, _ := cmd.Flags().GetBool("no-hooks")
+		noValidate, _ := cmd.Flags().GetBool("no-validate")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		jorge.SetHooksEnabled(!noHooks)
+		jorge.SetValidationEnabled(!noValidate)
+
+		action := "ls"
+		if len(args) > 0 {
+			action = args[0]
+		}
+
+		var err *jorge.EncapsulatedError
+		operation := "profile " + action
+		switch {
+		case action == "ls" && len(args) <= 1:
+			var profiles []jorge.Profile
+			if profiles, err = jorge.ListProfiles(); err == nil {
+				printResult(result{Operation: operation, Data: profiles}, func() {
+					for _, profile := range profiles {
+						switches := []string{}
+						for _, profileSwitch := range profile.Switches {
+							switches = append(switches, fmt.Sprintf("%s=%s", profileSwitch.Project, profileSwitch.Env))
+						}
+						fmt.Printf("%s\t%s\n", profile.Name, strings.Join(switches, " "))
+					}
+				})
+			}
+		case action == "use" && len(args) == 2:
+			var switches []jorge.ProfileSwitch
+			if switches, err = jorge.ProfileUse(args[1]); err == nil {
+				printResult(result{Operation: operation, Data: map[string]interface{}{"profile": args[1], "switches": switches}}, func() {
+					for _, profileSwitch := range switches {
+						fmt.Printf("Using environment %s in %s\n", profileSwitch.Env, profileSwitch.Project)
+					}
+				})
+			}
+		default:
+			exitWithMessage("profile", "Usage: jorge profile ls | use <name>")
+		}
+
+		if err != nil {
+			exitWithError(operation, err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.Flags().Bool("no-hooks", false, "Skip the hooks configured in .jorge/config.yml")
+	profileCmd.Flags().Bool("no-validate", false, "Skip the validation against .jorge/schema.yml")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Converts an environment to another format",
+	Long: `Converts the stored files of an environment to another format, without
+	using the environment. Secrets and shared environments are resolved. Without
+	an environment name the current environment is rendered.
+	Usage:
+
+	jorge render [env_name] --format ` + strings.Join(jorge.RenderFormats, "|") + ` [--to <file>] [--name <resource_name>]`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		format, _ := cmd.Flags().GetString("format")
+		to, _ := cmd.Flags().GetString("to")
+		name, _ := cmd.Flags().GetString("name")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		selectedEnv := ""
+		if len(args) > 0 {
+			selectedEnv = args[0]
+		}
+
+		data, err := jorge.RenderEnv(selectedEnv, format, name)
+
+		if err != nil {
+			exitWithError("render", err)
+		}
+
+		res := result{Operation: "render", Env: selectedEnv, BytesWritten: int64(len(data))}
+		if len(to) == 0 || to == "-" {
+			res.Data = map[string]string{"format": format, "contents": string(data)}
+			printResult(res, func() {
+				os.Stdout.Write(data)
+			})
+			return
+		}
+
+		if writeErr := os.WriteFile(to, data, 0600); writeErr != nil {
+			exitWithMessage("render", fmt.Sprintf("Could not write %s: %v", to, writeErr))
+		}
+
+		res.Data = map[string]string{"format": format, "file": to}
+		printResult(res, nil)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+	renderCmd.Flags().StringP("format", "f", jorge.FormatDotenv, "Output format: "+strings.Join(jorge.RenderFormats, ", "))
+	renderCmd.Flags().String("to", "", "Write to a file instead of stdout")
+	renderCmd.Flags().String("name", "", "Name of the Kubernetes resource (defaults to the environment name)")
+}
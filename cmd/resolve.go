@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// resolveCmd represents the resolve command
+var resolveCmd = &cobra.Command{
+	Use:   "resolve",
+	Short: "Finishes a commit that stopped on conflicts",
+	Long: `Commits the configuration file after a commit stopped on conflicts. When
+	the environment was changed since the configuration file was created from it,
+	jorge commit merges the changes and marks the conflicting ones in the file.
+	Fix them and remove the markers before resolving.
+	Usage:
+
+	jorge resolve`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		noHooks, _ := cmd.Flags().GetBool("no-hooks")
+		noValidate, _ := cmd.Flags().GetBool("no-validate")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		jorge.SetHooksEnabled(!noHooks)
+		jorge.SetValidationEnabled(!noValidate)
+
+		if err := jorge.Resolve(); err != nil {
+			exitWithError("resolve", err)
+		}
+
+		currentEnv, _ := jorge.GetCurrentEnv()
+		printResult(result{Operation: "resolve", Env: currentEnv}, func() {
+			fmt.Println("Conflicts resolved, env committed")
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+	resolveCmd.Flags().Bool("no-hooks", false, "Skip the hooks configured in .jorge/config.yml")
+	resolveCmd.Flags().Bool("no-validate", false, "Skip the validation against .jorge/schema.yml")
+}
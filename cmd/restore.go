@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/dpliakos/jorge/internal/jorge"
 	log "github.com/sirupsen/logrus"
@@ -15,32 +14,26 @@ var restoreCmd = &cobra.Command{
 	Short: "Restores the current configuration file with the copy that is saved in the .jorge dir",
 	Run: func(cmd *cobra.Command, args []string) {
 		debug, _ := cmd.Flags().GetBool("debug")
+		noHooks, _ := cmd.Flags().GetBool("no-hooks")
 
 		if debug {
 			log.SetLevel(log.DebugLevel)
 		}
 
-		err := jorge.RestoreEnv()
+		jorge.SetHooksEnabled(!noHooks)
 
-		if err != nil {
-			if debug && err.OriginalErr != nil {
-				fmt.Fprintf(os.Stderr, "%s\n", err.OriginalErr.Error())
-			}
-
-			fmt.Fprintf(os.Stderr, "%s\n", err.Message)
-			fmt.Fprintf(os.Stderr, "%s\n", err.Solution)
+		if err := jorge.RestoreEnv(); err != nil {
+			exitWithError("restore", err)
+		}
 
-			if err.Code > 0 {
-				os.Exit(err.Code)
-			} else {
-				os.Exit(1)
-			}
-		} else {
+		currentEnv, _ := jorge.GetCurrentEnv()
+		printResult(result{Operation: "restore", Env: currentEnv}, func() {
 			fmt.Println("Env restored")
-		}
+		})
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().Bool("no-hooks", false, "Skip the hooks configured in .jorge/config.yml")
 }
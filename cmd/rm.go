@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/dpliakos/jorge/internal/jorge"
 	log "github.com/sirupsen/logrus"
@@ -24,30 +23,18 @@ var rmCmd = &cobra.Command{
 			log.SetLevel(log.DebugLevel)
 		}
 
-		var selectedEnv string
-		if len(args) > 0 {
-			selectedEnv = args[0]
-		} else {
-			fmt.Fprintf(os.Stderr, "%s\n", "No environment specified")
-			os.Exit(1)
+		if len(args) == 0 {
+			exitWithMessage("rm", "No environment specified")
 		}
+		selectedEnv := args[0]
 
 		if err := jorge.RemoveEnv(selectedEnv); err != nil {
-			if debug && err.OriginalErr != nil {
-				fmt.Fprintf(os.Stderr, "%s\n", err.OriginalErr.Error())
-			}
-
-			fmt.Fprintf(os.Stderr, "%s\n", err.Message)
-			fmt.Fprintf(os.Stderr, "%s\n", err.Solution)
-
-			if err.Code > 0 {
-				os.Exit(err.Code)
-			} else {
-				os.Exit(1)
-			}
-		} else {
-			fmt.Println("Removed environment", selectedEnv)
+			exitWithError("rm", err)
 		}
+
+		printResult(result{Operation: "rm", Env: selectedEnv}, func() {
+			fmt.Println("Removed environment", selectedEnv)
+		})
 	},
 }
 
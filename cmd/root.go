@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/dpliakos/jorge/internal/jorge"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +14,13 @@ var rootCmd = &cobra.Command{
 	Use:     "jorge",
 	Version: "0.0.2",
 	Short:   "Manages different versions of a configuration file",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		project, _ := cmd.Flags().GetString("project")
+		stopAt, _ := cmd.Flags().GetString("stop-at")
+		jorge.SetProjectDir(project)
+		jorge.SetStopAt(stopAt)
+		setupOutput(cmd)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -20,10 +28,16 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
+		if isJSONOutput() {
+			printJSON(errorResult{Operation: rootCmd.Name(), Error: errorDetails{Code: 1, Message: err.Error()}, Warnings: []string{}})
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Prints debug messages")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", outputText, "Output format: text or json")
+	rootCmd.PersistentFlags().String("project", "", "Name of a project of the monorepo, or root of a jorge project, instead of searching for it from the current directory")
+	rootCmd.PersistentFlags().String("stop-at", "", "Stop the search for the project at the git root (git) or at the filesystem boundary (filesystem). Defaults to $JORGE_STOP_AT")
 }
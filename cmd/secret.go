@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// secretCmd represents the secret command
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manages the secrets that environments refer to",
+	Long: `Manages the secrets of the providers that can store them, such as the
+	local encrypted keyfile. The value of a new secret is read from the
+	standard input, so that it does not end up in the shell history.
+	Environments refer to secrets with ${secret:<name>} or
+	${secret:<provider>:<name>}.
+	Usage:
+
+	jorge secret ls
+	jorge secret set <name>
+	jorge secret rm <name>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		provider, _ := cmd.Flags().GetString("provider")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		if len(args) < 1 {
+			exitWithMessage("secret", "Usage: jorge secret ls | set <name> | rm <name>")
+		}
+
+		var err *jorge.EncapsulatedError
+		operation := "secret " + args[0]
+		switch action := args[0]; {
+		case action == "ls":
+			var names []string
+			if names, err = jorge.ListSecrets(provider); err == nil {
+				printResult(result{Operation: operation, Data: names}, func() {
+					for _, name := range names {
+						fmt.Println(name)
+					}
+				})
+			}
+		case action == "set" && len(args) == 2:
+			if isTerminal(os.Stdin) {
+				fmt.Fprintf(os.Stderr, "Value of %s: ", args[1])
+			}
+
+			value, readErr := bufio.NewReader(os.Stdin).ReadString('\n')
+			value = strings.TrimRight(value, "\r\n")
+			if len(value) == 0 && readErr != nil {
+				exitWithMessage(operation, "No value was given")
+			}
+
+			if err = jorge.SetSecret(provider, args[1], value); err == nil {
+				printResult(result{Operation: operation, Data: map[string]string{"secret": args[1]}}, func() {
+					fmt.Printf("Stored secret %s\n", args[1])
+				})
+			}
+		case action == "rm" && len(args) == 2:
+			if err = jorge.RemoveSecret(provider, args[1]); err == nil {
+				printResult(result{Operation: operation, Data: map[string]string{"secret": args[1]}}, func() {
+					fmt.Printf("Removed secret %s\n", args[1])
+				})
+			}
+		default:
+			exitWithMessage("secret", "Usage: jorge secret ls | set <name> | rm <name>")
+		}
+
+		if err != nil {
+			exitWithError(operation, err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+	secretCmd.Flags().StringP("provider", "p", "", "Secret provider to use instead of the default one of the project")
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Archives and restores every environment at once",
+	Long: `Archives every environment and config.yml under .jorge/snapshots.
+	Snapshots are also taken automatically before jorge rm, jorge adopt, store
+	upgrades and snapshot restores. The automatic ones are removed by the
+	retention policy of config.yml (snapshots.keep, 10 by default).
+	Usage:
+
+	jorge snapshot ls
+	jorge snapshot create [name]
+	jorge snapshot restore <name>
+	jorge snapshot rm <name>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		action := "ls"
+		if len(args) > 0 {
+			action = args[0]
+		}
+
+		var err *jorge.EncapsulatedError
+		operation := "snapshot " + action
+		switch {
+		case action == "ls" && len(args) <= 1:
+			var snapshots []jorge.Snapshot
+			if snapshots, err = jorge.ListSnapshots(); err == nil {
+				printResult(result{Operation: operation, Data: snapshots}, func() {
+					writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					for _, snapshot := range snapshots {
+						kind := "manual"
+						if snapshot.Automatic {
+							kind = "automatic"
+						}
+						fmt.Fprintf(writer, "%s\t%s\t%d B\t%s\n", snapshot.Name, kind, snapshot.Size, snapshot.Created.Local().Format("2006-01-02 15:04"))
+					}
+					writer.Flush()
+				})
+			}
+		case action == "create" && len(args) <= 2:
+			name := ""
+			if len(args) == 2 {
+				name = args[1]
+			}
+
+			var snapshot jorge.Snapshot
+			if snapshot, err = jorge.CreateSnapshot(name); err == nil {
+				printResult(result{Operation: operation, BytesWritten: snapshot.Size, Data: snapshot}, func() {
+					fmt.Printf("Created snapshot %s\n", snapshot.Name)
+				})
+			}
+		case action == "restore" && len(args) == 2:
+			if err = jorge.RestoreSnapshot(args[1]); err == nil {
+				printResult(result{Operation: operation, Data: map[string]string{"snapshot": args[1]}}, func() {
+					fmt.Printf("Restored snapshot %s. Run jorge restore to update the configuration file\n", args[1])
+				})
+			}
+		case action == "rm" && len(args) == 2:
+			if err = jorge.RemoveSnapshot(args[1]); err == nil {
+				printResult(result{Operation: operation, Data: map[string]string{"snapshot": args[1]}}, func() {
+					fmt.Printf("Removed snapshot %s\n", args[1])
+				})
+			}
+		default:
+			exitWithMessage("snapshot", "Usage: jorge snapshot ls | create [name] | restore <name> | rm <name>")
+		}
+
+		if err != nil {
+			exitWithError(operation, err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// stashCmd represents the stash command
+var stashCmd = &cobra.Command{
+	Use:   "stash",
+	Short: "Parks the changes of the configuration file",
+	Long: `Parks the uncommitted changes of the configuration file and restores it
+	to the stored version of the current environment. The parked changes can be
+	applied later, on the same or on another environment.
+	Usage:
+
+	jorge stash [push] [-m message]
+	jorge stash list
+	jorge stash pop [n]
+	jorge stash apply [n]
+	jorge stash drop [n]`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		message, _ := cmd.Flags().GetString("message")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		action := "push"
+		if len(args) > 0 {
+			action = args[0]
+		}
+
+		index := 0
+		if len(args) > 1 {
+			parsedIndex, parseErr := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(args[1], "stash@{"), "}"))
+			if parseErr != nil {
+				exitWithMessage("stash "+action, fmt.Sprintf("Invalid stash entry %s", args[1]))
+			}
+			index = parsedIndex
+		}
+
+		var entry jorge.StashEntry
+		var err *jorge.EncapsulatedError
+		operation := "stash " + action
+		switch action {
+		case "push":
+			if entry, err = jorge.StashPush(message); err == nil {
+				printResult(result{Operation: operation, Env: entry.Env, Data: entry}, func() {
+					fmt.Printf("Stashed the changes of %s\n", entry.Env)
+				})
+			}
+		case "list":
+			var entries []jorge.StashEntry
+			if entries, err = jorge.StashList(); err == nil {
+				printResult(result{Operation: operation, Data: entries}, func() {
+					for _, entry := range entries {
+						fmt.Printf("stash@{%d}: on %s: %s (%s)\n", entry.Index, entry.Env, entry.Message, entry.Created.Local().Format("2006-01-02 15:04"))
+					}
+				})
+			}
+		case "pop", "apply":
+			if entry, err = jorge.StashApply(index, action == "pop"); err == nil {
+				printResult(result{Operation: operation, Env: entry.Env, Data: entry}, func() {
+					fmt.Printf("Applied stash@{%d} of %s\n", index, entry.Env)
+				})
+			}
+		case "drop":
+			if entry, err = jorge.StashDrop(index); err == nil {
+				printResult(result{Operation: operation, Env: entry.Env, Data: entry}, func() {
+					fmt.Printf("Dropped stash@{%d} of %s\n", index, entry.Env)
+				})
+			}
+		default:
+			exitWithMessage("stash", fmt.Sprintf("Unknown action %s. Use push, list, pop, apply or drop", action))
+		}
+
+		if err != nil {
+			exitWithError(operation, err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(stashCmd)
+	stashCmd.Flags().StringP("message", "m", "", "Description of the stashed changes")
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Adds or removes a tag of an environment",
+	Long: `Labels an environment with tags that can be used to filter the
+	output of jorge ls.
+	Usage:
+
+	jorge tag <env_name> add <tag>
+	jorge tag <env_name> rm <tag>`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		if len(args) < 3 {
+			exitWithMessage("tag", "Usage: jorge tag <env_name> add|rm <tag>")
+		}
+
+		selectedEnv, action, tag := args[0], args[1], args[2]
+
+		var err *jorge.EncapsulatedError
+		switch action {
+		case "add":
+			err = jorge.AddEnvTag(selectedEnv, tag)
+		case "rm":
+			err = jorge.RemoveEnvTag(selectedEnv, tag)
+		default:
+			exitWithMessage("tag", fmt.Sprintf("Unknown action %s. Use add or rm", action))
+		}
+
+		if err != nil {
+			exitWithError("tag "+action, err)
+		}
+
+		printResult(result{Operation: "tag " + action, Env: selectedEnv, Data: map[string]string{"tag": tag}}, func() {
+			if action == "add" {
+				fmt.Printf("Tagged %s with %s\n", selectedEnv, tag)
+			} else {
+				fmt.Printf("Removed tag %s from %s\n", tag, selectedEnv)
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+}
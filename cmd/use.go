@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/dpliakos/jorge/internal/jorge"
 	log "github.com/sirupsen/logrus"
@@ -15,12 +14,18 @@ var useCmd = &cobra.Command{
 	Short: "Selects or creates an environment",
 	Run: func(cmd *cobra.Command, args []string) {
 		debug, _ := cmd.Flags().GetBool("debug")
+		noHooks, _ := cmd.Flags().GetBool("no-hooks")
+		noValidate, _ := cmd.Flags().GetBool("no-validate")
 		newEnv, _ := cmd.Flags().GetBool("new")
+		all, _ := cmd.Flags().GetBool("all")
 
 		if debug {
 			log.SetLevel(log.DebugLevel)
 		}
 
+		jorge.SetHooksEnabled(!noHooks)
+		jorge.SetValidationEnabled(!noValidate)
+
 		var selectedEnv string
 
 		if len(args) > 0 {
@@ -29,41 +34,51 @@ var useCmd = &cobra.Command{
 			selectedEnv = "default"
 		}
 
-		bytes, encErr := jorge.UseConfigFile(selectedEnv, newEnv)
-
-		if encErr != nil {
-			if debug && encErr.OriginalErr != nil {
-				fmt.Fprintf(os.Stderr, "%s\n", encErr.OriginalErr.Error())
-			}
-
-			_, err := fmt.Fprintf(os.Stderr, "%s\n", encErr.Message)
-			if err != nil {
-				return
-			}
-			_, err = fmt.Fprintf(os.Stderr, "%s\n", encErr.Solution)
-			if err != nil {
-				return
-			}
-
-			if encErr.Code > 0 {
-				os.Exit(encErr.Code)
-			} else {
-				os.Exit(1)
-			}
+		if all {
+			useAll(selectedEnv)
+			return
+		}
+
+		bytes, err := jorge.UseConfigFile(selectedEnv, newEnv)
+
+		if err != nil {
+			exitWithError("use", err)
 		} else if bytes < 0 {
-			fmt.Fprintln(os.Stderr, "Could not use the target file")
-			os.Exit(int(bytes))
-		} else if bytes == 0 {
-			fmt.Fprintln(os.Stderr, "Target file is empty. Nothing to do")
-		} else if bytes > 0 {
-			fmt.Println(fmt.Sprintf("Using environment %s", selectedEnv))
-		} else {
-			fmt.Fprintln(os.Stderr, "Undefined error")
+			exitWithMessage("use", "Could not use the target file")
 		}
+
+		printResult(result{Operation: "use", Env: selectedEnv, BytesWritten: bytes}, func() {
+			fmt.Println(fmt.Sprintf("Using environment %s", selectedEnv))
+		})
 	},
 }
 
+// useAll
+// Uses the environment in every project of the monorepo that has it
+func useAll(selectedEnv string) {
+	projects, err := jorge.UseAll(selectedEnv)
+
+	// The projects that were switched before the error are listed as well
+	printProjects := func() {
+		for _, project := range projects {
+			fmt.Printf("Using environment %s in %s\n", selectedEnv, project)
+		}
+	}
+
+	if err != nil {
+		if !isJSONOutput() {
+			printProjects()
+		}
+		exitWithError("use", err)
+	}
+
+	printResult(result{Operation: "use", Env: selectedEnv, Data: map[string][]string{"projects": projects}}, printProjects)
+}
+
 func init() {
 	rootCmd.AddCommand(useCmd)
+	useCmd.Flags().Bool("no-hooks", false, "Skip the hooks configured in .jorge/config.yml")
+	useCmd.Flags().Bool("no-validate", false, "Skip the validation against .jorge/schema.yml")
 	useCmd.Flags().BoolP("new", "n", false, "Create a new environment")
+	useCmd.Flags().Bool("all", false, "Use the environment in every project of the monorepo that has it")
 }
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Checks environments against the schema",
+	Long: `Checks the keys of the stored environments against the schema declared
+	in .jorge/schema.yml. Without arguments the current environment is checked.
+	Usage:
+
+	jorge validate
+	jorge validate <env_name>
+	jorge validate --all`,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		all, _ := cmd.Flags().GetBool("all")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		envNames := []string{""}
+		if len(args) > 0 {
+			envNames = args
+		}
+
+		var err *jorge.EncapsulatedError
+		if all {
+			var envs []jorge.EnvInfo
+			envs, err = jorge.ListEnvironments("")
+
+			envNames = []string{}
+			for _, env := range envs {
+				if env.Committed {
+					envNames = append(envNames, env.Name)
+				}
+			}
+		}
+
+		violations := []jorge.SchemaViolation{}
+		for _, envName := range envNames {
+			if err != nil {
+				break
+			}
+
+			var envViolations []jorge.SchemaViolation
+			envViolations, err = jorge.ValidateEnv(envName)
+			violations = append(violations, envViolations...)
+		}
+
+		if err != nil {
+			exitWithError("validate", err)
+		}
+
+		printResult(result{Operation: "validate", Data: violations}, func() {
+			for _, violation := range violations {
+				location := violation.Env
+				if len(violation.File) > 0 {
+					location = fmt.Sprintf("%s/%s", violation.Env, violation.File)
+				}
+				fmt.Printf("%s: %s: %s\n", location, violation.Key, violation.Problem)
+			}
+
+			if len(violations) == 0 {
+				fmt.Println("All keys match the schema")
+			}
+		})
+
+		if len(violations) > 0 {
+			os.Exit(129)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().BoolP("all", "a", false, "Check every environment")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dpliakos/jorge/internal/jorge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// whereCmd represents the where command
+var whereCmd = &cobra.Command{
+	Use:   "where",
+	Short: "Prints the paths of the project and its store",
+	Long: `Prints the root of the jorge project that the current directory belongs to and the
+	path of its store. The store is the .jorge directory at the root, unless $JORGE_DIR or a
+	.jorgerc file points elsewhere. A store that $JORGE_DIR points to outside of the project
+	belongs to the root that was recorded when it was initialized. The search for the root
+	can be stopped at the git root or at the filesystem boundary with --stop-at.
+	Usage:
+
+	jorge where
+	jorge where --store
+	jorge where --stop-at git`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		debug, _ := cmd.Flags().GetBool("debug")
+		rootOnly, _ := cmd.Flags().GetBool("root")
+		storeOnly, _ := cmd.Flags().GetBool("store")
+
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		location, err := jorge.Where()
+
+		if err != nil {
+			exitWithError("where", err)
+		}
+
+		printResult(result{Operation: "where", Data: location}, func() {
+			switch {
+			case rootOnly:
+				fmt.Println(location.Root)
+			case storeOnly:
+				fmt.Println(location.Store)
+			default:
+				if len(location.Project) > 0 {
+					fmt.Printf("project  %s\n", location.Project)
+				}
+				fmt.Printf("root     %s\n", location.Root)
+				fmt.Printf("store    %s (%s)\n", location.Store, location.Source)
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whereCmd)
+	whereCmd.Flags().Bool("root", false, "Print only the project root")
+	whereCmd.Flags().Bool("store", false, "Print only the store")
+}
@@ -0,0 +1,171 @@
+package jorge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AdoptResult
+// The outcome of `jorge adopt`. Collisions are the variants whose environment
+// already exists and were left untouched
+type AdoptResult struct {
+	Adopted    []ConfigVariant `json:"adopted" yaml:"adopted"`
+	Collisions []ConfigVariant `json:"collisions" yaml:"collisions"`
+}
+
+// importConfigVariants
+// Stores every variant found next to the tracked files (e.g. .env.staging next
+// to .env) as an environment of its own. The tracked files that have no
+// variant for an environment are stored with their current contents. Variants
+// of the existing environments are not imported and are returned as collisions.
+// When snapshot is set, the store is snapshotted before anything is imported
+func importConfigVariants(config JorgeConfig, pattern string, projectRoot string, existingEnvs []string, snapshot bool) ([]ConfigVariant, []ConfigVariant, *EncapsulatedError) {
+	variantsByEnv := map[string]map[string]ConfigVariant{}
+	envNames := []string{}
+	collisions := []ConfigVariant{}
+
+	for _, trackedFile := range config.TrackedFiles() {
+		variants, err := findConfigVariants(filepath.Join(projectRoot, trackedFile), pattern)
+		if err != nil {
+			return []ConfigVariant{}, []ConfigVariant{}, err
+		}
+
+		for _, variant := range variants {
+			if Contains(existingEnvs, variant.EnvName) {
+				collisions = append(collisions, variant)
+				continue
+			}
+
+			if _, found := variantsByEnv[variant.EnvName]; !found {
+				variantsByEnv[variant.EnvName] = map[string]ConfigVariant{}
+				envNames = append(envNames, variant.EnvName)
+			}
+			variantsByEnv[variant.EnvName][trackedFile] = variant
+		}
+	}
+
+	if snapshot && len(envNames) > 0 {
+		jorgeDir, err := getJorgeDir()
+		if err != nil {
+			return []ConfigVariant{}, collisions, err
+		}
+
+		if err := takeAutomaticSnapshot(jorgeDir, config.Snapshots, "adopt"); err != nil {
+			return []ConfigVariant{}, collisions, err
+		}
+	}
+
+	imported := []ConfigVariant{}
+	for _, envName := range envNames {
+		for _, trackedFile := range config.TrackedFiles() {
+			_, trackedName := filepath.Split(trackedFile)
+
+			source := filepath.Join(projectRoot, trackedFile)
+			variant, found := variantsByEnv[envName][trackedFile]
+			if found {
+				source = variant.Path
+			}
+
+			if _, err := storeConfigFileAs(source, envName, trackedName, true); err != nil {
+				return imported, collisions, err
+			}
+
+			if found {
+				imported = append(imported, variant)
+			}
+		}
+
+		log.Debug(fmt.Sprintf("Imported variant environment %s", envName))
+	}
+
+	return imported, collisions, nil
+}
+
+// Adopt
+// Creates an environment for every variant of the tracked files that matches
+// the glob pattern. The adopted files can be deleted or added to .gitignore
+// afterwards
+func Adopt(pattern string, deleteOriginals bool, ignoreOriginals bool) (AdoptResult, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return AdoptResult{}, err
+	}
+
+	projectRoot, err := resolveJorgeDir()
+	if err != nil {
+		return AdoptResult{}, err
+	}
+
+	existingEnvs, err := getEnvs()
+	if err != nil {
+		return AdoptResult{}, err
+	}
+
+	store, err := getJorgeDir()
+	if err != nil {
+		return AdoptResult{}, err
+	}
+
+	// The adopted environments are removed again when the originals can not
+	// be deleted or ignored
+	tx, err := beginTransaction(store, "import")
+	if err != nil {
+		return AdoptResult{}, err
+	}
+	defer tx.close()
+
+	gitignorePath := filepath.Join(projectRoot, ".gitignore")
+	tx.allow(gitignorePath)
+	if err := tx.trackAll([]string{filepath.Join(store, "envs"), gitignorePath}); err != nil {
+		return AdoptResult{}, err
+	}
+
+	adopted, collisions, err := importConfigVariants(config, pattern, projectRoot, existingEnvs, true)
+	result := AdoptResult{Adopted: adopted, Collisions: collisions}
+	if err != nil {
+		return result, err
+	}
+
+	for _, variant := range adopted {
+		if deleteOriginals {
+			tx.allow(variant.Path)
+			if err := tx.track(variant.Path); err != nil {
+				return result, err
+			}
+			if removeErr := os.Remove(variant.Path); removeErr != nil {
+				encErr := EncapsulatedError{
+					OriginalErr: removeErr,
+					Message:     ErrorCode.Str(E010),
+					Solution:    SolutionMessage.Str(S002, GetUser()),
+					Code:        10,
+				}
+				return result, &encErr
+			}
+			log.Debug(fmt.Sprintf("Deleted %s", variant.Path))
+		} else if ignoreOriginals {
+			relativePath, relErr := filepath.Rel(projectRoot, variant.Path)
+			if relErr != nil {
+				relativePath = variant.Path
+			}
+			relativePath = filepath.ToSlash(relativePath)
+
+			if ignored, _ := ExistsInFile(gitignorePath, relativePath); !ignored {
+				if appendErr := AppendToFile(gitignorePath, relativePath); appendErr != nil {
+					encErr := EncapsulatedError{
+						OriginalErr: appendErr,
+						Message:     ErrorCode.Str(E007),
+						Solution:    SolutionMessage.Str(S002, GetUser()),
+						Code:        7,
+					}
+					return result, &encErr
+				}
+			}
+		}
+	}
+
+	tx.commit()
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAdoptReportsCollisions(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "staging"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".env"), []byte("A=default"), 0600)
+	defer os.Remove(filepath.Join(testingRoot, ".env"))
+	os.WriteFile(filepath.Join(testingRoot, ".env.staging"), []byte("A=staging"), 0600)
+	defer os.Remove(filepath.Join(testingRoot, ".env.staging"))
+	os.WriteFile(filepath.Join(testingRoot, ".env.local"), []byte("A=local"), 0600)
+	defer os.Remove(filepath.Join(testingRoot, ".env.local"))
+
+	result, err := Adopt("", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Adopted) != 1 || result.Adopted[0].EnvName != "local" {
+		t.Fatalf("Expected to adopt local, but adopted %v", result.Adopted)
+	} else if len(result.Collisions) != 1 || result.Collisions[0].EnvName != "staging" {
+		t.Fatalf("Expected a collision with staging, but found %v", result.Collisions)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(testingRoot, ".jorge", "envs", "local", ".env")); err != nil {
+		t.Fatal(err)
+	} else if string(data) != "A=local" {
+		t.Fatalf("Expected %s, but found %s", "A=local", string(data))
+	}
+
+	if _, err := os.Stat(filepath.Join(testingRoot, ".env.local")); err == nil {
+		t.Fatal("Adopted file was not deleted")
+	} else if _, err := os.Stat(filepath.Join(testingRoot, ".env.staging")); err != nil {
+		t.Fatal("File of an existing environment was deleted")
+	}
+}
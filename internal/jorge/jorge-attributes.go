@@ -0,0 +1,183 @@
+package jorge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// FileAttributes
+// The attributes of a working configuration file, recorded when it is stored
+// and applied again when the environment is used
+type FileAttributes struct {
+	Mode     string    `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Uid      *int      `json:"uid,omitempty" yaml:"uid,omitempty"`
+	Gid      *int      `json:"gid,omitempty" yaml:"gid,omitempty"`
+	Modified time.Time `json:"modified" yaml:"modified,omitempty"`
+}
+
+// ParseFileMode
+// Parses an octal file mode such as 0600
+func ParseFileMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	if mode > 0777 {
+		return 0, fmt.Errorf("%s has bits other than the permissions", value)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// fileModeError
+// Wraps an invalid file mode
+func fileModeError(err error, value string) *EncapsulatedError {
+	encErr := EncapsulatedError{
+		OriginalErr: err,
+		Message:     ErrorCode.Str(E145),
+		Solution:    SolutionMessage.Str(S138, value),
+		Code:        145,
+	}
+	return &encErr
+}
+
+// getFileModeOverride
+// Returns the mode that the project sets for every working file, or 0 when
+// the project does not set one
+func getFileModeOverride(config JorgeConfig) (os.FileMode, *EncapsulatedError) {
+	if len(config.FileMode) == 0 {
+		return 0, nil
+	}
+
+	mode, parseErr := ParseFileMode(config.FileMode)
+	if parseErr != nil {
+		return 0, fileModeError(parseErr, config.FileMode)
+	}
+
+	return mode, nil
+}
+
+// readFileAttributes
+// Returns the attributes of a file. The owner is only known on unix systems
+func readFileAttributes(info os.FileInfo) FileAttributes {
+	attributes := FileAttributes{
+		Mode:     fmt.Sprintf("%04o", info.Mode().Perm()),
+		Modified: info.ModTime().UTC(),
+	}
+
+	if uid, gid, found := fileOwner(info); found {
+		attributes.Uid, attributes.Gid = &uid, &gid
+	}
+
+	return attributes
+}
+
+// recordFileAttributes
+// Records the attributes of a working file in the metadata of the environment
+// it was stored to. Environments without metadata are left as they are
+func recordFileAttributes(envName string, fileName string, info os.FileInfo) *EncapsulatedError {
+	envDir, err := getEnvDirPath(envName)
+	if err != nil {
+		return err
+	}
+
+	if _, statErr := os.Stat(filepath.Join(envDir, envMetaFileName)); statErr != nil {
+		return nil
+	}
+
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	if meta.Files == nil {
+		meta.Files = map[string]FileAttributes{}
+	}
+	meta.Files[fileName] = readFileAttributes(info)
+
+	return setEnvMeta(envName, meta)
+}
+
+// getRecordedFileMode
+// Returns the mode that applies to a working file, which is the mode set by
+// the project, or the mode recorded when the file was stored. It returns 0
+// when there is neither
+func getRecordedFileMode(config JorgeConfig, envName string, fileName string) (os.FileMode, *EncapsulatedError) {
+	if mode, err := getFileModeOverride(config); err != nil || mode != 0 {
+		return mode, err
+	}
+
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return 0, err
+	}
+
+	attributes, found := meta.Files[fileName]
+	if !found || len(attributes.Mode) == 0 {
+		return 0, nil
+	}
+
+	mode, parseErr := ParseFileMode(attributes.Mode)
+	if parseErr != nil {
+		return 0, fileModeError(parseErr, attributes.Mode)
+	}
+
+	return mode, nil
+}
+
+// applyFileAttributes
+// Applies the recorded attributes of a working file. The owner and the
+// modification time are only applied when the user is permitted to, while a
+// mode that can not be applied is an error, since the file may hold secrets
+func applyFileAttributes(path string, config JorgeConfig, envName string, fileName string) *EncapsulatedError {
+	mode, err := getRecordedFileMode(config, envName, fileName)
+	if err != nil {
+		return err
+	}
+
+	if mode != 0 {
+		if chmodErr := os.Chmod(path, mode); chmodErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: chmodErr,
+				Message:     ErrorCode.Str(E007),
+				Solution:    SolutionMessage.Str(S002, GetUser()),
+				Code:        7,
+			}
+			return &encErr
+		}
+	}
+
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	attributes, found := meta.Files[fileName]
+	if !found {
+		return nil
+	}
+
+	if attributes.Uid != nil && attributes.Gid != nil {
+		if info, statErr := os.Stat(path); statErr == nil {
+			if uid, gid, found := fileOwner(info); found && (uid != *attributes.Uid || gid != *attributes.Gid) {
+				if chownErr := os.Chown(path, *attributes.Uid, *attributes.Gid); chownErr != nil {
+					log.Debug(fmt.Sprintf("Could not change the owner of %s: %v", path, chownErr))
+				}
+			}
+		}
+	}
+
+	if !attributes.Modified.IsZero() {
+		if chtimesErr := os.Chtimes(path, time.Now(), attributes.Modified); chtimesErr != nil {
+			log.Debug(fmt.Sprintf("Could not change the modification time of %s: %v", path, chtimesErr))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileAttributesAreKept(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "default"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.Remove(filepath.Join(testingRoot, ".env"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", envMetaFileName), []byte("revision: 1\n"), 0600)
+
+	workingFilePath := filepath.Join(testingRoot, ".env")
+	modified := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	os.WriteFile(workingFilePath, []byte("HOST=localhost\r\n"), 0600)
+	os.Chmod(workingFilePath, 0640)
+	os.Chtimes(workingFilePath, modified, modified)
+
+	if err := CommitCurrentEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, _ := os.Stat(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env")); info.Mode().Perm() != privateFileMode {
+		t.Fatalf("Stored file has mode %04o", info.Mode().Perm())
+	}
+
+	os.Remove(workingFilePath)
+	if err := RestoreEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Stat(workingFilePath); err != nil || info.Mode().Perm() != 0640 || !info.ModTime().Equal(modified) {
+		t.Fatalf("Attributes were not applied %v (%v)", info, err)
+	}
+
+	if data, _ := os.ReadFile(workingFilePath); string(data) != "HOST=localhost\r\n" {
+		t.Fatalf("Line endings were not kept: %q", data)
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\nfileMode: \"0600\"\n"), 0600)
+	if err := RestoreEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, _ := os.Stat(workingFilePath); info.Mode().Perm() != 0600 {
+		t.Fatalf("fileMode was not applied: %04o", info.Mode().Perm())
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\nfileMode: rw\n"), 0600)
+	if err := RestoreEnv(); err == nil || err.Code != 145 {
+		t.Fatalf("Expected code %d, but found %v", 145, err)
+	}
+}
+
+func TestMatchLineEndings(t *testing.T) {
+	data, err := addConfigKey("app.json", []byte("{\r\n  \"a\": 1\r\n}\r\n"), []byte("{\"b\": 2}"), "b", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "{\r\n  \"a\": 1,\r\n  \"b\": 2\r\n}\r\n" {
+		t.Fatalf("Unexpected file %q", data)
+	}
+
+	if data := matchLineEndings([]byte("a: 1\n"), []byte("a: 1\nb: 2\n")); string(data) != "a: 1\nb: 2\n" {
+		t.Fatalf("Unexpected file %q", data)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package jorge
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner
+// Returns the user and group that own a file
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return int(stat.Uid), int(stat.Gid), true
+}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package jorge
+
+import "os"
+
+// fileOwner
+// Files have no numeric owner on windows
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,200 @@
+package jorge
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// configCandidatePatterns are the glob patterns, relative to the project root,
+// of the files that are offered as configuration files by `jorge init`
+var configCandidatePatterns = []string{
+	".env*",
+	"appsettings*.json",
+	"application*.yml",
+	"application*.yaml",
+	"config/*.toml",
+}
+
+// ConfigVariant
+// A file that lives next to a tracked configuration file and holds another
+// version of it, e.g. .env.staging next to .env
+type ConfigVariant struct {
+	Path    string `json:"path" yaml:"path"`
+	EnvName string `json:"envName" yaml:"envName"`
+}
+
+// findConfigCandidates
+// Returns the paths, relative to root, of the files that look like
+// configuration files
+func findConfigCandidates(root string) []string {
+	candidates := []string{}
+
+	for _, pattern := range configCandidatePatterns {
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			log.Debug(fmt.Sprintf("Invalid candidate pattern %s: %v", pattern, err))
+			continue
+		}
+
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+
+			if relativePath, err := filepath.Rel(root, match); err == nil && !Contains(candidates, relativePath) {
+				candidates = append(candidates, relativePath)
+			}
+		}
+	}
+
+	sort.Strings(candidates)
+	return candidates
+}
+
+// splitConfigFileName
+// Splits a file name to the part before and the part after the variant name.
+// For dotfiles without extension (.env) the whole name is the stem
+func splitConfigFileName(name string) (string, string) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	if len(stem) == 0 {
+		return name, ""
+	}
+
+	return stem, ext
+}
+
+// variantEnvName
+// Derives the environment name of a variant from the part of its name that
+// differs from the tracked file, e.g. staging for .env.staging or Development
+// for appsettings.Development.json. It returns an empty string when the file
+// is not a variant of the tracked file
+func variantEnvName(trackedName string, variantName string) string {
+	if trackedName == variantName {
+		return ""
+	}
+
+	stem, ext := splitConfigFileName(trackedName)
+
+	if !strings.HasPrefix(variantName, stem) || !strings.HasSuffix(variantName, ext) || len(variantName) <= len(stem)+len(ext) {
+		return ""
+	}
+
+	suffix := variantName[len(stem) : len(variantName)-len(ext)]
+	if !strings.HasPrefix(suffix, ".") && !strings.HasPrefix(suffix, "-") {
+		return ""
+	}
+
+	return strings.Trim(suffix, ".-_")
+}
+
+// findConfigVariants
+// Returns the files next to the tracked configuration file that match the glob
+// pattern and can be named after their suffix. An empty pattern matches the
+// variants that share the name and the extension of the tracked file
+func findConfigVariants(trackedPath string, pattern string) ([]ConfigVariant, *EncapsulatedError) {
+	dir, trackedName := filepath.Split(trackedPath)
+
+	if len(pattern) == 0 {
+		stem, ext := splitConfigFileName(trackedName)
+		pattern = stem + "?*" + ext
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: err,
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S109, pattern, "a valid glob pattern"),
+			Code:        118,
+		}
+		return []ConfigVariant{}, &encErr
+	}
+
+	variants := []ConfigVariant{}
+	for _, match := range matches {
+		_, name := filepath.Split(match)
+
+		if info, err := os.Stat(match); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		envName := variantEnvName(trackedName, name)
+		if len(envName) == 0 {
+			log.Debug(fmt.Sprintf("Could not derive an environment name from %s", match))
+			continue
+		}
+
+		variants = append(variants, ConfigVariant{Path: match, EnvName: envName})
+	}
+
+	return variants, nil
+}
+
+// requestConfigFileFromUser
+// It shows a cli prompt with the configuration files found in the project and
+// accepts either the number of one of them or a path to the user's
+// configuration file
+func requestConfigFileFromUser(input io.Reader) (string, *EncapsulatedError) {
+	candidates := findConfigCandidates(".")
+
+	if len(candidates) > 0 {
+		fmt.Println("Found the following configuration files:")
+		for i, candidate := range candidates {
+			fmt.Printf("  %d) %s\n", i+1, candidate)
+		}
+		fmt.Print("Select a file by its number or type its path: ")
+	} else {
+		fmt.Print("Config file path: ")
+	}
+
+	line, readErr := bufio.NewReader(input).ReadString('\n')
+	line = strings.TrimSpace(line)
+
+	if len(line) == 0 {
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E121),
+			Solution:    SolutionMessage.Str(S112),
+			Code:        121,
+		}
+		return "", &encErr
+	}
+
+	if index, err := strconv.Atoi(line); err == nil && index >= 1 && index <= len(candidates) {
+		return candidates[index-1], nil
+	}
+
+	configFileRelativePath := filepath.Clean(line)
+
+	if _, err := os.Stat(configFileRelativePath); err != nil {
+		if os.IsNotExist(err) {
+			encErr := EncapsulatedError{
+				OriginalErr: err,
+				Message:     ErrorCode.Str(E006),
+				Solution:    SolutionMessage.Str(S003, configFileRelativePath),
+				Code:        6,
+			}
+			return "", &encErr
+		} else {
+			encErr := EncapsulatedError{
+				OriginalErr: err,
+				Message:     ErrorCode.Str(E005),
+				Solution:    SolutionMessage.Str(S003, configFileRelativePath),
+				Code:        5,
+			}
+			return "", &encErr
+		}
+	}
+
+	return configFileRelativePath, nil
+}
@@ -0,0 +1,83 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVariantEnvName(t *testing.T) {
+	cases := []struct {
+		tracked  string
+		variant  string
+		expected string
+	}{
+		{".env", ".env.staging", "staging"},
+		{".env", ".env", ""},
+		{".env", ".envrc", ""},
+		{"appsettings.json", "appsettings.Development.json", "Development"},
+		{"application.yml", "application-dev.yml", "dev"},
+		{"application.yml", "application.yaml", ""},
+	}
+
+	for _, c := range cases {
+		if name := variantEnvName(c.tracked, c.variant); name != c.expected {
+			t.Errorf("Expected %s for %s, but found %s", c.expected, c.variant, name)
+		}
+	}
+}
+
+func TestFindConfigCandidates(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.Mkdir(filepath.Join(testingRoot, "config"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, "config"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".env"), []byte("A=1"), 0600)
+	defer os.Remove(filepath.Join(testingRoot, ".env"))
+	os.WriteFile(filepath.Join(testingRoot, "config", "app.toml"), []byte("a = 1"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, "notes.txt"), []byte(""), 0600)
+	defer os.Remove(filepath.Join(testingRoot, "notes.txt"))
+
+	candidates := findConfigCandidates(testingRoot)
+	if strings.Join(candidates, ",") != strings.Join([]string{".env", filepath.Join("config", "app.toml")}, ",") {
+		t.Fatalf("Unexpected candidates %v", candidates)
+	}
+}
+
+func TestInitImportsVariants(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.WriteFile(filepath.Join(testingRoot, ".env"), []byte("A=default"), 0600)
+	defer os.Remove(filepath.Join(testingRoot, ".env"))
+	os.WriteFile(filepath.Join(testingRoot, ".env.staging"), []byte("A=staging"), 0600)
+	defer os.Remove(filepath.Join(testingRoot, ".env.staging"))
+	defer os.Remove(filepath.Join(testingRoot, ".gitignore"))
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	if _, err := Init([]string{}, false); err == nil {
+		t.Fatal("Initialized a project without a config file in non interactive mode")
+	} else if _, statErr := os.Stat(filepath.Join(testingRoot, ".jorge")); statErr == nil {
+		t.Fatal("Created the .jorge dir although the initialization failed")
+	}
+
+	importedEnvs, err := Init([]string{".env"}, false)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(importedEnvs) != 1 || importedEnvs[0] != "staging" {
+		t.Fatalf("Expected to import staging, but imported %v", importedEnvs)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env")); err != nil {
+		t.Fatal(err)
+	} else if string(data) != "A=staging" {
+		t.Fatalf("Expected %s, but found %s", "A=staging", string(data))
+	}
+}
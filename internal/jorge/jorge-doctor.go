@@ -0,0 +1,277 @@
+package jorge
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const privateDirMode os.FileMode = 0700
+const privateFileMode os.FileMode = 0600
+
+// DoctorFinding
+// A problem that `jorge doctor` found in the jorge project
+type DoctorFinding struct {
+	Check   string `json:"check" yaml:"check"`
+	Problem string `json:"problem" yaml:"problem"`
+	Fixable bool   `json:"fixable" yaml:"fixable"`
+	Fixed   bool   `json:"fixed" yaml:"fixed"`
+}
+
+type doctor struct {
+	fix      bool
+	findings []DoctorFinding
+}
+
+// report
+// Records a problem. When the problem can be repaired and the doctor runs in
+// fix mode, the repair function is executed
+func (d *doctor) report(check string, problem string, repair func() error) {
+	finding := DoctorFinding{
+		Check:   check,
+		Problem: problem,
+		Fixable: repair != nil,
+	}
+
+	if d.fix && repair != nil {
+		if err := repair(); err != nil {
+			log.Warn(fmt.Sprintf("Could not fix '%s': %v", problem, err))
+		} else {
+			finding.Fixed = true
+		}
+	}
+
+	d.findings = append(d.findings, finding)
+}
+
+// checkPermissions
+// Reports paths whose mode is not the expected one, e.g. files that are
+// accessible by users other than the owner or that are executable
+func (d *doctor) checkPermissions(path string, expected os.FileMode) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	if info.Mode().Perm() != expected {
+		d.report("permissions", fmt.Sprintf("%s has mode %04o instead of %04o", path, info.Mode().Perm(), expected), func() error {
+			return os.Chmod(path, expected)
+		})
+	}
+}
+
+// Doctor
+// Checks the jorge project for inconsistencies and returns the problems that
+// were found. When fix is set, the problems that can be safely repaired are
+// repaired
+func Doctor(fix bool) ([]DoctorFinding, *EncapsulatedError) {
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return []DoctorFinding{}, err
+	}
+
+	projectRoot, err := resolveJorgeDir()
+	if err != nil {
+		return []DoctorFinding{}, err
+	}
+
+	d := doctor{fix: fix, findings: []DoctorFinding{}}
+	d.checkPermissions(jorgeDir, privateDirMode)
+
+	// The interrupted operations are undone before the rest is checked
+	for _, unfinished := range findUnfinishedOperations(jorgeDir) {
+		journalDir := unfinished.Journal
+		d.report("journal", fmt.Sprintf("the %s operation that started at %s did not finish", unfinished.Operation, unfinished.Started.Local().Format("2006-01-02 15:04")), func() error {
+			return rollbackJournal(journalDir)
+		})
+	}
+
+	configFilePath := filepath.Join(jorgeDir, configFileName)
+	configData, readErr := ioutil.ReadFile(configFilePath)
+	if readErr != nil {
+		d.report("config", fmt.Sprintf("%s can not be read: %v", configFilePath, readErr), nil)
+		return d.findings, nil
+	}
+	d.checkPermissions(configFilePath, privateFileMode)
+
+	var config JorgeConfig
+	if ymlErr := yaml.Unmarshal(configData, &config); ymlErr != nil {
+		d.report("config", fmt.Sprintf("%s is not valid yaml: %v", configFilePath, ymlErr), nil)
+		return d.findings, nil
+	}
+
+	// The store at the root of a monorepo may only host the named projects
+	if len(config.ConfigFilePath) == 0 && len(config.Projects) > 0 {
+		for _, project := range config.Projects {
+			projectStore := filepath.Join(jorgeDir, projectsDirName, filepath.FromSlash(project))
+			if _, statErr := os.Stat(filepath.Join(projectStore, configFileName)); statErr != nil {
+				d.report("projects", fmt.Sprintf("project %s has no store at %s", project, projectStore), nil)
+			}
+		}
+		return d.findings, nil
+	}
+
+	envsDir := filepath.Join(jorgeDir, "envs")
+	envs := []string{}
+	if entries, readEnvsErr := ioutil.ReadDir(envsDir); readEnvsErr != nil {
+		d.report("envs", fmt.Sprintf("%s can not be read: %v", envsDir, readEnvsErr), func() error {
+			return os.Mkdir(envsDir, privateDirMode)
+		})
+	} else {
+		d.checkPermissions(envsDir, privateDirMode)
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				envs = append(envs, entry.Name())
+			} else {
+				d.report("envs", fmt.Sprintf("%s is not an environment directory", filepath.Join(envsDir, entry.Name())), nil)
+			}
+		}
+	}
+
+	if len(config.CurrentEnv) == 0 {
+		var repair func() error
+		if len(envs) > 0 {
+			fallbackEnv := envs[0]
+			if Contains(envs, "default") {
+				fallbackEnv = "default"
+			}
+
+			repair = func() error {
+				_, err := setInternalConfig(JorgeConfig{CurrentEnv: fallbackEnv})
+				return encapsulatedToError(err)
+			}
+		}
+		d.report("config", "currentEnv is not set in config.yml", repair)
+	}
+
+	if len(config.ConfigFilePath) == 0 {
+		d.report("config", "configFilePath is not set in config.yml", nil)
+		return d.findings, nil
+	}
+
+	if config.Mode != "" && config.Mode != ModeCopy && config.Mode != ModeSymlink {
+		d.report("config", fmt.Sprintf("mode %s in config.yml is not one of %s, %s", config.Mode, ModeCopy, ModeSymlink), nil)
+	}
+
+	fileModeOverride, fileModeErr := getFileModeOverride(config)
+	if fileModeErr != nil {
+		d.report("config", fmt.Sprintf("fileMode %s in config.yml is not an octal file mode", config.FileMode), nil)
+	}
+
+	currentEnvExists := Contains(envs, config.CurrentEnv)
+	trackedFileNames := []string{}
+
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+		trackedFileNames = append(trackedFileNames, trackedFileName)
+		trackedFilePath := filepath.Join(projectRoot, trackedFile)
+
+		if filepath.IsAbs(trackedFile) || !isWithinPath(projectRoot, trackedFilePath) {
+			d.report("config", fmt.Sprintf("configuration file %s is outside of the project %s", trackedFile, projectRoot), nil)
+		} else if _, statErr := os.Stat(trackedFilePath); statErr != nil {
+			var repair func() error
+			if currentEnvExists {
+				repair = func() error {
+					_, err := setConfigAsMain(trackedFilePath, config.CurrentEnv)
+					return encapsulatedToError(err)
+				}
+			}
+			d.report("config", fmt.Sprintf("configuration file %s does not exist", trackedFilePath), repair)
+		} else if storedFilePath := filepath.Join(envsDir, config.CurrentEnv, trackedFileName); isSymlink(trackedFilePath) && currentEnvExists && !isLinkedTo(trackedFilePath, storedFilePath) {
+			d.report("symlink", fmt.Sprintf("configuration file %s is a link that does not point to %s", trackedFilePath, storedFilePath), func() error {
+				_, err := setConfigAsMain(trackedFilePath, config.CurrentEnv)
+				return encapsulatedToError(err)
+			})
+		} else if info, lstatErr := os.Lstat(trackedFilePath); lstatErr == nil && info.Mode().IsRegular() && info.Mode().Perm()&0044 != 0 && info.Mode().Perm() != fileModeOverride {
+			// Projects that set a fileMode have chosen who can read the files
+			d.report("secrets", fmt.Sprintf("configuration file %s is readable by other users (mode %04o)", trackedFilePath, info.Mode().Perm()), func() error {
+				return os.Chmod(trackedFilePath, info.Mode().Perm()&^0077)
+			})
+		}
+	}
+
+	if len(config.CurrentEnv) > 0 && !currentEnvExists {
+		repair := func() error {
+			for _, trackedFile := range config.TrackedFiles() {
+				if _, err := StoreConfigFile(filepath.Join(projectRoot, trackedFile), config.CurrentEnv); err != nil {
+					return encapsulatedToError(err)
+				}
+			}
+			return nil
+		}
+		d.report("envs", fmt.Sprintf("current environment %s does not exist under %s", config.CurrentEnv, envsDir), repair)
+	}
+
+	for _, env := range envs {
+		envDir := filepath.Join(envsDir, env)
+		d.checkPermissions(envDir, privateDirMode)
+
+		entries, readEnvErr := ioutil.ReadDir(envDir)
+		if readEnvErr != nil {
+			d.report("envs", fmt.Sprintf("%s can not be read: %v", envDir, readEnvErr), nil)
+			continue
+		}
+
+		storedFileNames := []string{}
+		for _, entry := range entries {
+			entryPath := filepath.Join(envDir, entry.Name())
+
+			switch {
+			case Contains(trackedFileNames, entry.Name()) && entry.Mode().IsRegular():
+				storedFileNames = append(storedFileNames, entry.Name())
+				d.checkPermissions(entryPath, privateFileMode)
+			case entry.Name() == envMetaFileName && entry.Mode().IsRegular():
+				d.checkPermissions(entryPath, privateFileMode)
+			case entry.Name() == baseDirName && entry.IsDir():
+				d.checkPermissions(entryPath, privateDirMode)
+			default:
+				d.report("envs", fmt.Sprintf("unexpected file %s in environment %s", entryPath, env), nil)
+			}
+		}
+
+		for _, trackedFileName := range trackedFileNames {
+			if !Contains(storedFileNames, trackedFileName) {
+				d.report("envs", fmt.Sprintf("environment %s does not contain %s", env, trackedFileName), nil)
+			}
+		}
+
+		if meta, err := getEnvMeta(env); err == nil {
+			for _, ref := range meta.Include {
+				name, err := parseIncludeRef(ref)
+				if err == nil {
+					_, err = readSharedEnv(name)
+				}
+
+				if err != nil {
+					d.report("include", fmt.Sprintf("environment %s includes %s: %s", env, ref, err.Message), nil)
+				}
+			}
+		}
+	}
+
+	gitignorePath := filepath.Join(projectRoot, ".gitignore")
+	if ignorePath, inProject := getStoreIgnorePath(projectRoot, jorgeDir); !inProject {
+		log.Debug(fmt.Sprintf("The store %s is outside of the project", jorgeDir))
+	} else if ignored, _ := ExistsInFile(gitignorePath, ignorePath); !ignored {
+		d.report("gitignore", fmt.Sprintf("%s is not listed in %s", ignorePath, gitignorePath), func() error {
+			return AppendToFile(gitignorePath, ignorePath)
+		})
+	}
+
+	return d.findings, nil
+}
+
+// encapsulatedToError
+// Converts an EncapsulatedError to an error, keeping nil values nil
+func encapsulatedToError(err *EncapsulatedError) error {
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %v", err.Message, err.OriginalErr)
+}
@@ -0,0 +1,98 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDoctorFindsProblems(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "mainTestConfig"), []byte("mock config contents"), 0644)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "unexpectedFile"), []byte(""), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600)
+	defer os.Remove(filepath.Join(testingRoot, ".gitignore"))
+	defer os.Remove(filepath.Join(testingRoot, "mainTestConfig"))
+
+	findings, err := Doctor(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := map[string]int{}
+	for _, finding := range findings {
+		checks[finding.Check]++
+	}
+
+	if checks["permissions"] != 1 || checks["envs"] != 1 || checks["gitignore"] != 1 || checks["config"] != 1 {
+		t.Fatalf("Unexpected findings %v", findings)
+	}
+
+	if _, err := Doctor(true); err != nil {
+		t.Fatal(err)
+	}
+
+	if findings, err := Doctor(false); err != nil {
+		t.Fatal(err)
+	} else if len(findings) != 1 || findings[0].Fixable {
+		t.Fatalf("Expected only the unexpected file to remain, but found %v", findings)
+	}
+
+	if info, err := os.Stat(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "mainTestConfig")); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Fatalf("Permissions were not fixed: %04o", info.Mode().Perm())
+	}
+
+	if data, err := os.ReadFile(filepath.Join(testingRoot, "mainTestConfig")); err != nil || string(data) != "mock config contents" {
+		t.Fatal("Configuration file was not restored")
+	}
+}
+
+func TestDoctorChecksConfigMode(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	// ..env is a file of the project, not a path outside of it
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "..env"), []byte("A=1\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, "..env"), []byte("A=1\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: ..env\n"), 0700)
+	os.Chmod(filepath.Join(testingRoot, ".jorge", "config.yml"), 0700)
+	defer os.Remove(filepath.Join(testingRoot, "..env"))
+	defer os.Remove(filepath.Join(testingRoot, ".gitignore"))
+
+	findings, err := Doctor(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := map[string]int{}
+	for _, finding := range findings {
+		checks[finding.Check]++
+	}
+
+	if checks["permissions"] != 1 || checks["config"] != 0 {
+		t.Fatalf("Unexpected findings %v", findings)
+	}
+
+	os.Remove(filepath.Join(testingRoot, ".jorge", "config.yml"))
+	if err := SelectEnvironment("mockEnv"); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Stat(filepath.Join(testingRoot, ".jorge", "config.yml")); err != nil || info.Mode().Perm() != privateFileMode {
+		t.Fatalf("Unexpected mode of config.yml (%v)", err)
+	}
+}
@@ -0,0 +1,251 @@
+package jorge
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// getEditor
+// Returns the editor of the user from $VISUAL or $EDITOR, falling back to the
+// default editor of the platform
+func getEditor() string {
+	for _, variable := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(variable)); len(editor) > 0 {
+			return editor
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// editorCommand
+// Creates the command that opens the file in the editor. The editor runs
+// through the shell, since it often contains arguments (e.g. `code --wait`).
+// It stays in the process group of jorge, so that it can use the terminal
+func editorCommand(editor string, path string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", editor+` "`+path+`"`)
+	}
+
+	return exec.Command("sh", "-c", editor+` "$1"`, "jorge-edit", path)
+}
+
+// EditEnv
+// Opens a stored file of an environment in the editor of the user without
+// using the environment. The file is copied to a private temporary file,
+// which is removed when the editor exits or jorge is interrupted. The edited
+// file is checked against the schema and stored like a committed file. An
+// empty file name selects the main configuration file. It returns whether
+// the environment was changed
+func EditEnv(envName string, fileName string, input io.Reader) (bool, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return false, err
+	}
+
+	trackedFileNames := []string{}
+	trackedFilePath := ""
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+		trackedFileNames = append(trackedFileNames, trackedFileName)
+
+		if (len(fileName) == 0 && len(trackedFilePath) == 0) || fileName == trackedFileName || fileName == trackedFile {
+			fileName, trackedFilePath = trackedFileName, trackedFile
+		}
+	}
+
+	if len(trackedFilePath) == 0 {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("%s is not tracked by jorge", fileName),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S126, strings.Join(trackedFileNames, ", ")),
+			Code:        118,
+		}
+		return false, &encErr
+	}
+
+	storedData, err := readStoredFile(envName, fileName)
+	if err != nil {
+		return false, err
+	}
+
+	// The working copy of the current environment follows the edit, unless it
+	// holds changes of its own
+	envDir, _ := getEnvDirPath(envName)
+	wasClean := envName == config.CurrentEnv && !isWorkingCopyDirty(config, envDir)
+
+	tempFile, tempErr := ioutil.TempFile("", "jorge-edit-*-"+fileName)
+	if tempErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: tempErr,
+			Message:     ErrorCode.Str(E131),
+			Solution:    SolutionMessage.Str(S002, GetUser()),
+			Code:        131,
+		}
+		return false, &encErr
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+
+	// The temporary file holds the configuration in plain text, so it is
+	// removed even when jorge is interrupted while the editor is open
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer close(signals)
+	defer signal.Stop(signals)
+	go func() {
+		if _, received := <-signals; received {
+			os.Remove(tempPath)
+			os.Exit(130)
+		}
+	}()
+
+	_, writeErr := tempFile.Write(storedData)
+	if closeErr := tempFile.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: writeErr,
+			Message:     ErrorCode.Str(E131),
+			Solution:    SolutionMessage.Str(S002, GetUser()),
+			Code:        131,
+		}
+		return false, &encErr
+	}
+
+	editor := getEditor()
+	reader := bufio.NewReader(input)
+
+	for {
+		editCmd := editorCommand(editor, tempPath)
+		editCmd.Stdin = os.Stdin
+		editCmd.Stdout = os.Stdout
+		editCmd.Stderr = os.Stderr
+
+		log.Debug(fmt.Sprintf("Opening %s with %s", tempPath, editor))
+		if runErr := editCmd.Run(); runErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: runErr,
+				Message:     ErrorCode.Str(E131),
+				Solution:    SolutionMessage.Str(S125, editor),
+				Code:        131,
+			}
+			return false, &encErr
+		}
+
+		editedData, readErr := ioutil.ReadFile(tempPath)
+		if readErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: readErr,
+				Message:     ErrorCode.Str(E131),
+				Solution:    SolutionMessage.Str(S003, tempPath),
+				Code:        131,
+			}
+			return false, &encErr
+		}
+
+		if bytes.Equal(editedData, storedData) {
+			log.Debug("The file was not changed")
+			return false, nil
+		}
+
+		validationErr := validateEditedFile(config, envName, fileName, editedData)
+		if validationErr == nil {
+			break
+		}
+
+		fmt.Fprintf(os.Stderr, "%s\n%s\n", validationErr.Message, validationErr.Solution)
+		fmt.Fprint(os.Stderr, "Edit the file again? [Y/n] ")
+
+		answer, answerErr := reader.ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answerErr != nil || answer == "n" || answer == "no" {
+			return false, validationErr
+		}
+	}
+
+	// The temporary file says nothing about the mode or the owner of the
+	// working file, so the recorded attributes are kept
+	if _, err := storeConfigFileAs(tempPath, envName, fileName, false); err != nil {
+		return false, err
+	}
+
+	if wasClean {
+		projectRoot, err := resolveJorgeDir()
+		if err != nil {
+			return true, err
+		}
+
+		if _, err := setConfigAsMain(filepath.Join(projectRoot, trackedFilePath), envName); err != nil {
+			return true, err
+		}
+
+		if err := recordWorkingCopyBase(config, envName, nil); err != nil {
+			log.Warn(fmt.Sprintf("%s: %s", err.Message, err.OriginalErr))
+		}
+	}
+
+	return true, nil
+}
+
+// validateEditedFile
+// Checks the edited version of a stored file, together with the other stored
+// files of the environment, against the schema
+func validateEditedFile(config JorgeConfig, envName string, fileName string, editedData []byte) *EncapsulatedError {
+	renderedData, _, err := renderConfigFile(envName, fileName, editedData)
+	if err != nil {
+		return err
+	}
+
+	entries, ok := parseConfigEntries(fileName, renderedData)
+	if !ok {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("%s can not be parsed", fileName),
+			Message:     ErrorCode.Str(E131),
+			Solution:    SolutionMessage.Str(S124, fileName, "the file is not valid"),
+			Code:        131,
+		}
+		return &encErr
+	}
+
+	if !validationEnabled {
+		return nil
+	}
+
+	schema, found, err := getSchema()
+	if err != nil || !found {
+		return err
+	}
+
+	files, err := storedFileEntries(config, envName)
+	if err != nil {
+		return err
+	}
+
+	for i := range files {
+		if files[i].fileName == fileName {
+			files[i].entries = entries
+		}
+	}
+
+	if violations := validateEntries(schema, envName, files); len(violations) > 0 {
+		return schemaViolationsError(violations)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEditEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The test editor is a shell command")
+	}
+
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "default"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "staging"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env"), []byte("HOST=stagnig\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", envMetaFileName), []byte("revision: 3\nfiles:\n  .env:\n    mode: \"0644\"\n"), 0600)
+
+	os.WriteFile(filepath.Join(testingRoot, "editor.sh"), []byte("#!/bin/sh\necho HOST=staging > \"$1\"\n"), 0700)
+	defer os.Remove(filepath.Join(testingRoot, "editor.sh"))
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", filepath.Join(testingRoot, "editor.sh"))
+
+	if changed, err := EditEnv("staging", "", strings.NewReader("")); err != nil || !changed {
+		t.Fatalf("Expected the environment to change (%v)", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env")); string(data) != "HOST=staging\n" {
+		t.Fatalf("Unexpected contents %s", data)
+	}
+
+	if meta, err := getEnvMeta("staging"); err != nil || meta.Revision != 4 {
+		t.Fatalf("Expected revision %d, but found %d (%v)", 4, meta.Revision, err)
+	}
+
+	if meta, _ := getEnvMeta("staging"); meta.Files[".env"].Mode != "0644" {
+		t.Fatalf("Expected mode %s, but found %v", "0644", meta.Files)
+	}
+
+	if changed, err := EditEnv("staging", "", strings.NewReader("")); err != nil || changed {
+		t.Fatalf("Expected no changes (%v)", err)
+	}
+
+	if _, err := EditEnv("staging", "missing.json", strings.NewReader("")); err == nil {
+		t.Fatal("Edited a file that is not tracked")
+	}
+}
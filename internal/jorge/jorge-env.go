@@ -0,0 +1,133 @@
+package jorge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Shells that `jorge env` writes statements for
+const (
+	ShellBash       = "bash"
+	ShellZsh        = "zsh"
+	ShellFish       = "fish"
+	ShellPowerShell = "powershell"
+)
+
+// Shells lists the shells of `jorge env`
+var Shells = []string{ShellBash, ShellZsh, ShellFish, ShellPowerShell}
+
+// quoteFishValue
+// Quotes a value for fish, where single quotes only interpret \\ and \'
+func quoteFishValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+}
+
+// quotePowerShellValue
+// Quotes a value for PowerShell, where single quotes are escaped by doubling.
+// PowerShell also treats the typographic single quotes as quotes
+func quotePowerShellValue(value string) string {
+	var quoted strings.Builder
+	quoted.WriteByte('\'')
+	for _, r := range value {
+		switch r {
+		case '\'', '\u2018', '\u2019', '\u201A', '\u201B':
+			quoted.WriteRune(r)
+		}
+		quoted.WriteRune(r)
+	}
+	quoted.WriteByte('\'')
+
+	return quoted.String()
+}
+
+// detectShell
+// Returns the shell of the user from $SHELL, falling back to bash
+func detectShell() string {
+	_, shell := filepath.Split(os.Getenv("SHELL"))
+	if Contains(Shells, shell) {
+		return shell
+	}
+
+	return ShellBash
+}
+
+// shellStatements
+// Writes the statements that set, or unset, the entries in the given shell
+func shellStatements(entries []DotenvEntry, shell string, unset bool) ([]byte, *EncapsulatedError) {
+	if !Contains(Shells, shell) {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("unknown shell %s", shell),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S109, shell, strings.Join(Shells, ", ")),
+			Code:        118,
+		}
+		return []byte{}, &encErr
+	}
+
+	var builder strings.Builder
+	for _, entry := range entries {
+		if !envVariableNamePattern.MatchString(entry.Key) {
+			return []byte{}, renderError(shell, entry.Key, "the key is not a valid variable name")
+		}
+
+		switch {
+		case shell == ShellFish && unset:
+			fmt.Fprintf(&builder, "set -e %s\n", entry.Key)
+		case shell == ShellFish:
+			fmt.Fprintf(&builder, "set -gx %s %s\n", entry.Key, quoteFishValue(entry.Value))
+		case shell == ShellPowerShell && unset:
+			fmt.Fprintf(&builder, "Remove-Item Env:%s -ErrorAction SilentlyContinue\n", entry.Key)
+		case shell == ShellPowerShell:
+			fmt.Fprintf(&builder, "$env:%s = %s\n", entry.Key, quotePowerShellValue(entry.Value))
+		case unset:
+			fmt.Fprintf(&builder, "unset %s\n", entry.Key)
+		default:
+			fmt.Fprintf(&builder, "export %s=%s\n", entry.Key, quotePosixValue(entry.Value))
+		}
+	}
+
+	return []byte(builder.String()), nil
+}
+
+// ShellEnv
+// Returns the statements that export the KEY=VALUE entries of an environment
+// in a shell, to be evaluated by it. With unset, the statements remove them
+// instead. An empty environment name selects the current environment and an
+// empty shell is detected from $SHELL
+func ShellEnv(envName string, shell string, unset bool) ([]byte, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if len(envName) == 0 {
+		envName = config.CurrentEnv
+	}
+
+	if len(shell) == 0 {
+		shell = detectShell()
+	}
+
+	entries, err := envVariables(config, envName)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	// A key that appears more than once is exported with its last value, so
+	// it is unset once
+	if unset {
+		unique := []DotenvEntry{}
+		seen := map[string]bool{}
+		for _, entry := range entries {
+			if !seen[entry.Key] {
+				seen[entry.Key] = true
+				unique = append(unique, entry)
+			}
+		}
+		entries = unique
+	}
+
+	return shellStatements(entries, shell, unset)
+}
@@ -0,0 +1,41 @@
+package jorge
+
+import (
+	"testing"
+)
+
+func TestShellStatements(t *testing.T) {
+	entries := []DotenvEntry{{Key: "MESSAGE", Value: "it's $HOME\\n"}}
+
+	cases := []struct {
+		shell      string
+		unset      bool
+		statements string
+	}{
+		{ShellBash, false, "export MESSAGE='it'\\''s $HOME\\n'\n"},
+		{ShellZsh, true, "unset MESSAGE\n"},
+		{ShellFish, false, "set -gx MESSAGE 'it\\'s $HOME\\\\n'\n"},
+		{ShellFish, true, "set -e MESSAGE\n"},
+		{ShellPowerShell, false, "$env:MESSAGE = 'it''s $HOME\\n'\n"},
+		{ShellPowerShell, true, "Remove-Item Env:MESSAGE -ErrorAction SilentlyContinue\n"},
+	}
+
+	for _, c := range cases {
+		if data, err := shellStatements(entries, c.shell, c.unset); err != nil || string(data) != c.statements {
+			t.Errorf("%s (unset %v): expected %q, but found %q (%v)", c.shell, c.unset, c.statements, data, err)
+		}
+	}
+
+	typographic := []DotenvEntry{{Key: "MESSAGE", Value: "\u2018a\u2019 \u201Ab\u201B"}}
+	if data, err := shellStatements(typographic, ShellPowerShell, false); err != nil || string(data) != "$env:MESSAGE = '\u2018\u2018a\u2019\u2019 \u201A\u201Ab\u201B\u201B'\n" {
+		t.Errorf("Unexpected statements %q (%v)", data, err)
+	}
+
+	if _, err := shellStatements(entries, "tcsh", false); err == nil || err.Code != 118 {
+		t.Fatalf("Expected code %d, but found %v", 118, err)
+	}
+
+	if _, err := shellStatements([]DotenvEntry{{Key: "1ST", Value: ""}}, ShellBash, false); err == nil || err.Code != 143 {
+		t.Fatalf("Expected code %d, but found %v", 143, err)
+	}
+}
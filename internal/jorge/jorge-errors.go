@@ -19,6 +19,7 @@ const (
 	E007 ErrorCode = "Could not write to file"
 	E008 ErrorCode = "Error constructing path"
 	E009 ErrorCode = "Could not delete the target directory"
+	E010 ErrorCode = "Could not delete the target file"
 
 	// E100 Application level errors. >= 100
 	E100 = "Active directory does not belong in a jorge project"
@@ -34,6 +35,48 @@ const (
 	E110 = "Could not store configuration file"
 	E111 = "Environment does not exist"
 	E112 = "Can not delete the active environment"
+	E113 = "Hook command failed"
+	E114 = "Hook command timed out"
+	E115 = "Could not read the environment metadata"
+	E116 = "Could not write the environment metadata"
+	E117 = "Invalid expiry date"
+	E118 = "Invalid option"
+	E119 = "The jorge directory was created by a newer version of jorge"
+	E120 = "Could not upgrade the jorge directory"
+	E121 = "No configuration file was given"
+	E122 = "Configuration files with the same name can not be tracked together"
+	E123 = "Could not access the global jorge store"
+	E124 = "Shared environment does not exist"
+	E125 = "Could not resolve secret"
+	E126 = "The secret provider can not store secrets"
+	E127 = "Could not access the secrets keyfile"
+	E128 = "Could not run the command"
+	E129 = "The environment does not match the schema"
+	E130 = "Could not read the schema"
+	E131 = "Could not edit the environment"
+	E132 = "No local changes to stash"
+	E133 = "Stash entry does not exist"
+	E134 = "The working configuration file has uncommitted changes"
+	E135 = "Could not update the stash"
+	E136 = "Could not record the version the working copy was created from"
+	E137 = "The working configuration file has merge conflicts"
+	E138 = "There are no merge conflicts to resolve"
+	E139 = "Could not create the snapshot"
+	E140 = "Snapshot does not exist"
+	E141 = "Could not restore the snapshot"
+	E142 = "Snapshot already exists"
+	E143 = "The environment can not be rendered in the requested format"
+	E144 = "Could not write the materialized file"
+	E145 = "Invalid file mode"
+	E146 = "Invalid .jorgerc file"
+	E147 = "Project already exists"
+	E148 = "Project directory does not exist"
+	E149 = "Profile does not exist"
+	E150 = "The profile can not be used"
+	E151 = "Could not record the changes of the operation"
+	E152 = "The stored configuration file can not be parsed"
+	E153 = "Shared environments can only be included in KEY=VALUE files"
+	E154 = "The project of the store can not be determined"
 )
 
 const (
@@ -46,11 +89,53 @@ const (
 	// S100 application level messages
 	S100 = "Please run `jorge init` to initialize a jorge project"
 	S101 = "Do not try to initialize a directory which is already a jorge project"
-	S102 = "Backup configuration files manually by copying files under ./.jorge/envs and initialize the jorge project again using `jorge init`"
+	S102 = "Run `jorge doctor --fix` to repair the jorge directory. If the problem persists, backup configuration files manually by copying files under ./.jorge/envs and initialize the jorge project again using `jorge init`"
 	S103 = "Make sure user %s has write privileges to .jorge directory"
 	S104 = "Please use an environment name that is not already in use. You can use `jorge ls` to see the list of current environments"
 	S105 = "You can create environment by running `jorge use -n %s`"
 	S106 = "Please select another environment or create a new one"
+	S107 = "Fix the hook `%s` in .jorge/config.yml or run the command with --no-hooks"
+	S108 = "Please use the YYYY-MM-DD format for the date (found %s) or `never` to remove it"
+	S109 = "Unknown value %s. Please use one of: %s"
+	S110 = "The jorge directory has version %d. Please upgrade jorge to use it"
+	S111 = "A backup of the jorge directory was kept at %s"
+	S112 = "Please declare the configuration file with `jorge init --config <path>`"
+	S113 = "Please track only one file named %s"
+	S114 = "Make sure user %s has write privileges to %s or set $XDG_DATA_HOME to a writable directory"
+	S115 = "Add it with `jorge global add %s <file>` or remove it from the environment with `jorge include <env_name> rm shared/%s`"
+	S116 = "Please refer to shared environments as shared/<name> (found %s)"
+	S117 = "You can use `jorge global ls` to see the list of shared environments"
+	S118 = "Make sure secret %s is available from the %s secret provider. Secrets of the keyfile provider can be stored with `jorge secret set <name>`"
+	S119 = "Manage the secrets of the %s provider with its own tools or use `--provider keyfile`"
+	S120 = "Make sure %s is readable and that the key in %s or $JORGE_SECRETS_KEY is the one it was written with"
+	S121 = "Make sure %s is installed and can be found in your PATH"
+	S122 = "Check the `secrets` section of .jorge/config.yml for the %s provider"
+	S123 = "Fix the following keys or run the command with --no-validate:\n%s"
+	S124 = "Fix %s: %s"
+	S125 = "Make sure $VISUAL or $EDITOR points to an editor that exits successfully (found %s)"
+	S126 = "The environment tracks the files: %s"
+	S127 = "Use `jorge stash list` to see the stash entries"
+	S128 = "Commit them with `jorge commit`, park them with `jorge stash push` or discard them with `jorge restore`"
+	S129 = "Fix the conflicts marked in %s and run `jorge resolve`, or discard the working copy with `jorge restore`"
+	S130 = "`jorge resolve` finishes a commit that stopped on conflicts, use `jorge commit` instead"
+	S131 = "Snapshot names can not contain path separators or start with auto- (found %s)"
+	S132 = "Use `jorge snapshot ls` to see the snapshots"
+	S133 = "%s is not a valid snapshot: %s"
+	S134 = "Choose another name or remove the snapshot with `jorge snapshot rm %s`"
+	S135 = "The %s format can not hold %s: %s"
+	S136 = "Make sure user %s can write to %s"
+	S137 = "%s is the working configuration file, use `jorge use %s` instead"
+	S138 = "%s is not an octal file mode such as 0600"
+	S139 = "Fix %s, it must hold the path of the store, e.g. dir: ~/jorge/project"
+	S140 = "%s is already a project of %s, use `jorge --project %s` to act on it"
+	S141 = "The name of a project is the path of its directory relative to the root of the monorepo (found %s under %s)"
+	S142 = "Define the profile under profiles in .jorge/config.yml. The defined profiles are: %s"
+	S143 = "Fix the profile %s in .jorge/config.yml: %v"
+	S144 = "%s is outside of the project or holds the journal of the operation, so it can not be changed by it"
+	S145 = "%s is not a valid environment name. Use a name other than . or .. without path separators"
+	S146 = "Shared environments are prepended to KEY=VALUE files such as .env, but the project only tracks %s"
+	S147 = "%s does not record the root of its project. Add a .jorgerc file at the project root or use --project <root>"
+	S148 = "%s belongs to the project at %s. Run jorge from that directory or use --project %s"
 )
 
 func (e ErrorCode) Str() string {
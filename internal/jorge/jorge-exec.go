@@ -0,0 +1,114 @@
+package jorge
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// envVariables
+// Returns the KEY=VALUE entries of the rendered configuration files of an
+// environment, with the included environments and the secrets resolved. The
+// keys that are missing get their default value from the schema
+func envVariables(config JorgeConfig, envName string) ([]DotenvEntry, *EncapsulatedError) {
+	envDir, err := getEnvDirPath(envName)
+	if err != nil {
+		return []DotenvEntry{}, err
+	}
+
+	entries := []DotenvEntry{}
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+		storedFilePath := filepath.Join(envDir, trackedFileName)
+
+		data, readErr := ioutil.ReadFile(storedFilePath)
+		if readErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: readErr,
+				Message:     ErrorCode.Str(E107),
+				Solution:    SolutionMessage.Str(S003, storedFilePath),
+				Code:        107,
+			}
+			return []DotenvEntry{}, &encErr
+		}
+
+		renderedData, _, err := renderConfigFile(envName, trackedFileName, data)
+		if err != nil {
+			return []DotenvEntry{}, err
+		}
+
+		entries = append(entries, parseDotenv(renderedData)...)
+	}
+
+	return append(schemaDefaults(entries), entries...), nil
+}
+
+// Exec
+// Runs a command with the KEY=VALUE entries of an environment added to its
+// environment variables, without writing them to the configuration file. An
+// empty environment name selects the current environment. It returns the exit
+// code of the command
+func Exec(envName string, command []string) (int, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return -1, err
+	}
+
+	if len(envName) == 0 {
+		envName = config.CurrentEnv
+	}
+
+	if len(command) == 0 {
+		encErr := EncapsulatedError{
+			OriginalErr: errors.New("no command was given"),
+			Message:     ErrorCode.Str(E128),
+			Solution:    "Usage: jorge exec [env_name] -- <command> [args...]",
+			Code:        128,
+		}
+		return -1, &encErr
+	}
+
+	entries, err := envVariables(config, envName)
+	if err != nil {
+		return -1, err
+	}
+
+	execCmd := exec.Command(command[0], command[1:]...)
+	execCmd.Stdin = os.Stdin
+	execCmd.Stdout = commandOutput
+	execCmd.Stderr = os.Stderr
+	execCmd.Env = os.Environ()
+	for _, entry := range entries {
+		execCmd.Env = append(execCmd.Env, entry.Key+"="+entry.Value)
+	}
+
+	// The command receives the interrupts of the terminal itself, jorge only
+	// waits for it to exit
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	log.Debug(fmt.Sprintf("Running %v with %d variables of %s", command, len(entries), envName))
+	runErr := execCmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		return exitErr.ExitCode(), nil
+	} else if runErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: runErr,
+			Message:     ErrorCode.Str(E128),
+			Solution:    SolutionMessage.Str(S121, command[0]),
+			Code:        128,
+		}
+		return -1, &encErr
+	}
+
+	return 0, nil
+}
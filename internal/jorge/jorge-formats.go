@@ -0,0 +1,300 @@
+package jorge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DotenvEntry
+// A KEY=VALUE line of a dotenv file
+type DotenvEntry struct {
+	Key   string `json:"key" yaml:"key"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// parseDotenv
+// Returns the KEY=VALUE lines of a dotenv file in the order they appear.
+// Comments, blank lines and lines of any other format are skipped. Double
+// quoted values support the \n, \r, \t, \$, \" and \\ escapes, single quoted
+// values are taken literally and unquoted values end at an inline comment
+func parseDotenv(data []byte) []DotenvEntry {
+	entries := []DotenvEntry{}
+
+	for _, line := range splitLines(data) {
+		key := dotenvKey(line)
+		if len(key) == 0 {
+			continue
+		}
+
+		value := strings.TrimSpace(line[strings.Index(line, "=")+1:])
+		entries = append(entries, DotenvEntry{Key: key, Value: parseDotenvValue(value)})
+	}
+
+	return entries
+}
+
+// parseDotenvValue
+// Removes the quotes and the inline comment of a dotenv value
+func parseDotenvValue(value string) string {
+	switch {
+	case len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`):
+		replacer := strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\$`, "$", `\"`, `"`, `\\`, `\`)
+		return replacer.Replace(value[1 : len(value)-1])
+	case len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'"):
+		return value[1 : len(value)-1]
+	}
+
+	if index := strings.Index(value, " #"); index >= 0 {
+		value = strings.TrimSpace(value[:index])
+	}
+
+	return value
+}
+
+// parseConfigEntries
+// Returns the keys and the values of a configuration file. JSON and YAML files
+// are flattened to dotted keys (e.g. database.host) and every other file is
+// read as KEY=VALUE lines, which also covers the top level keys of TOML files.
+// The second return value is false when the file can not be parsed
+func parseConfigEntries(fileName string, data []byte) ([]DotenvEntry, bool) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		// Numbers are kept as written, since float64 prints 1000000 as 1e+06
+		var document interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			return []DotenvEntry{}, false
+		}
+		if _, err := decoder.Token(); err != io.EOF {
+			return []DotenvEntry{}, false
+		}
+		return flattenConfig("", document), true
+	case ".yml", ".yaml":
+		var document interface{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return []DotenvEntry{}, false
+		}
+		return flattenConfig("", document), true
+	default:
+		return parseDotenv(data), true
+	}
+}
+
+// isDotenvStyleFile
+// Determines whether a file is read as KEY=VALUE lines rather than as a JSON
+// or YAML document
+func isDotenvStyleFile(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json", ".yml", ".yaml":
+		return false
+	default:
+		return true
+	}
+}
+
+// flattenConfig
+// Converts a decoded JSON or YAML document to entries with dotted keys. The
+// keys of every level are sorted, so that the order of the entries is stable
+func flattenConfig(prefix string, value interface{}) []DotenvEntry {
+	joinKey := func(key string) string {
+		if len(prefix) == 0 {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	entries := []DotenvEntry{}
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		keys := []string{}
+		for key := range typedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			entries = append(entries, flattenConfig(joinKey(key), typedValue[key])...)
+		}
+	case map[interface{}]interface{}:
+		keys := []string{}
+		values := map[string]interface{}{}
+		for key, nestedValue := range typedValue {
+			keys = append(keys, fmt.Sprint(key))
+			values[fmt.Sprint(key)] = nestedValue
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			entries = append(entries, flattenConfig(joinKey(key), values[key])...)
+		}
+	case []interface{}:
+		for i, nestedValue := range typedValue {
+			entries = append(entries, flattenConfig(joinKey(fmt.Sprint(i)), nestedValue)...)
+		}
+	case nil:
+		if len(prefix) > 0 {
+			entries = append(entries, DotenvEntry{Key: prefix, Value: ""})
+		}
+	default:
+		entries = append(entries, DotenvEntry{Key: prefix, Value: fmt.Sprint(typedValue)})
+	}
+
+	return entries
+}
+
+// matchLineEndings
+// Converts the line endings of a file that was encoded again to the line
+// endings of the original file, so that files with \r\n keep them
+func matchLineEndings(original []byte, encoded []byte) []byte {
+	if !strings.Contains(string(original), "\r\n") {
+		return encoded
+	}
+
+	return []byte(strings.ReplaceAll(strings.ReplaceAll(string(encoded), "\r\n", "\n"), "\n", "\r\n"))
+}
+
+// decodeOrderedJSON
+// Decodes a JSON document without losing the order of its keys or the
+// precision of its numbers. Objects are decoded to yaml.MapSlice, like the
+// YAML documents, and numbers to json.Number
+func decodeOrderedJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	value, err := decodeOrderedJSONValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON document")
+	}
+
+	return value, nil
+}
+
+// decodeOrderedJSONValue
+// Decodes the next value of the decoder
+func decodeOrderedJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := yaml.MapSlice{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeOrderedJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			object = append(object, yaml.MapItem{Key: key, Value: value})
+		}
+
+		_, err := decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrderedJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			array = append(array, value)
+		}
+
+		_, err := decoder.Token()
+		return array, err
+	default:
+		return token, nil
+	}
+}
+
+// encodeOrderedJSON
+// Encodes a document decoded by decodeOrderedJSON as indented JSON, keeping
+// the order of the keys
+func encodeOrderedJSON(value interface{}) ([]byte, error) {
+	var compact bytes.Buffer
+	if err := writeOrderedJSON(&compact, value); err != nil {
+		return nil, err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, compact.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+
+	return append(indented.Bytes(), '\n'), nil
+}
+
+// writeOrderedJSON
+// Writes a value as compact JSON
+func writeOrderedJSON(buffer *bytes.Buffer, value interface{}) error {
+	switch typedValue := value.(type) {
+	case yaml.MapSlice:
+		buffer.WriteByte('{')
+		for i, item := range typedValue {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+
+			key, _ := json.Marshal(fmt.Sprint(item.Key))
+			buffer.Write(key)
+			buffer.WriteByte(':')
+
+			if err := writeOrderedJSON(buffer, item.Value); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typedValue))
+		for key := range typedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		object := yaml.MapSlice{}
+		for _, key := range keys {
+			object = append(object, yaml.MapItem{Key: key, Value: typedValue[key]})
+		}
+		return writeOrderedJSON(buffer, object)
+	case []interface{}:
+		buffer.WriteByte('[')
+		for i, item := range typedValue {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+
+			if err := writeOrderedJSON(buffer, item); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	default:
+		encoded, err := json.Marshal(typedValue)
+		if err != nil {
+			return err
+		}
+		buffer.Write(encoded)
+	}
+
+	return nil
+}
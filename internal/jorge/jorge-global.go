@@ -0,0 +1,459 @@
+package jorge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const globalStoreDirName = "jorge"
+const sharedEnvsDirName = "shared"
+const sharedRefPrefix = "shared/"
+
+// includeBeginMarker and includeEndMarker wrap the contents of a shared
+// environment in the rendered configuration file, so that they can be removed
+// again when the file is stored
+const includeBeginMarker = "# >>> jorge include"
+const includeEndMarker = "# <<< jorge include"
+
+// SharedEnv
+// A configuration fragment of the global store that can be included by the
+// environments of any project
+type SharedEnv struct {
+	Name     string    `json:"name" yaml:"name"`
+	Size     int64     `json:"size" yaml:"size"`
+	Modified time.Time `json:"modified" yaml:"modified"`
+}
+
+// getGlobalStoreDir
+// Returns the path to the user level store. It lives under $XDG_DATA_HOME and
+// falls back to ~/.local/share when the variable is not set
+func getGlobalStoreDir() (string, *EncapsulatedError) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+
+	if len(dataHome) == 0 {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: err,
+				Message:     ErrorCode.Str(E123),
+				Solution:    SolutionMessage.Str(S114, GetUser(), "$HOME"),
+				Code:        123,
+			}
+			return "", &encErr
+		}
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+
+	return filepath.Join(dataHome, globalStoreDirName), nil
+}
+
+// getSharedEnvsDir
+// Returns the path to the directory that holds the shared environments
+func getSharedEnvsDir() (string, *EncapsulatedError) {
+	globalStoreDir, err := getGlobalStoreDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(globalStoreDir, sharedEnvsDirName), nil
+}
+
+// validateSharedEnvName
+// Shared environments are stored as plain files, so their names can not
+// contain path separators
+func validateSharedEnvName(name string) *EncapsulatedError {
+	if len(name) == 0 || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("invalid shared environment name %q", name),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S116, name),
+			Code:        118,
+		}
+		return &encErr
+	}
+
+	return nil
+}
+
+// parseIncludeRef
+// Returns the name of the shared environment that an include (shared/<name>)
+// refers to
+func parseIncludeRef(ref string) (string, *EncapsulatedError) {
+	if !strings.HasPrefix(ref, sharedRefPrefix) {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("invalid include %q", ref),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S116, ref),
+			Code:        118,
+		}
+		return "", &encErr
+	}
+
+	name := strings.TrimPrefix(ref, sharedRefPrefix)
+	if err := validateSharedEnvName(name); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// readSharedEnv
+// Returns the contents of a shared environment
+func readSharedEnv(name string) ([]byte, *EncapsulatedError) {
+	sharedEnvsDir, err := getSharedEnvsDir()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	data, readErr := ioutil.ReadFile(filepath.Join(sharedEnvsDir, name))
+	if readErr != nil {
+		if errors.Is(readErr, os.ErrNotExist) {
+			encErr := EncapsulatedError{
+				OriginalErr: readErr,
+				Message:     ErrorCode.Str(E124),
+				Solution:    SolutionMessage.Str(S115, name, name),
+				Code:        124,
+			}
+			return []byte{}, &encErr
+		}
+
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E123),
+			Solution:    SolutionMessage.Str(S003, filepath.Join(sharedEnvsDir, name)),
+			Code:        123,
+		}
+		return []byte{}, &encErr
+	}
+
+	return data, nil
+}
+
+// ListSharedEnvs
+// Returns the shared environments of the global store
+func ListSharedEnvs() ([]SharedEnv, *EncapsulatedError) {
+	sharedEnvsDir, err := getSharedEnvsDir()
+	if err != nil {
+		return []SharedEnv{}, err
+	}
+
+	entries, readErr := ioutil.ReadDir(sharedEnvsDir)
+	if errors.Is(readErr, os.ErrNotExist) {
+		return []SharedEnv{}, nil
+	} else if readErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E123),
+			Solution:    SolutionMessage.Str(S003, sharedEnvsDir),
+			Code:        123,
+		}
+		return []SharedEnv{}, &encErr
+	}
+
+	sharedEnvs := []SharedEnv{}
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+
+		sharedEnvs = append(sharedEnvs, SharedEnv{
+			Name:     entry.Name(),
+			Size:     entry.Size(),
+			Modified: entry.ModTime(),
+		})
+	}
+
+	return sharedEnvs, nil
+}
+
+// AddSharedEnv
+// Stores the contents of a file as a shared environment, replacing the shared
+// environment with the same name
+func AddSharedEnv(name string, path string) *EncapsulatedError {
+	if err := validateSharedEnvName(name); err != nil {
+		return err
+	}
+
+	data, readErr := ioutil.ReadFile(path)
+	if readErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E107),
+			Solution:    SolutionMessage.Str(S003, path),
+			Code:        107,
+		}
+		return &encErr
+	}
+
+	sharedEnvsDir, err := getSharedEnvsDir()
+	if err != nil {
+		return err
+	}
+
+	if mkdirErr := os.MkdirAll(sharedEnvsDir, privateDirMode); mkdirErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: mkdirErr,
+			Message:     ErrorCode.Str(E123),
+			Solution:    SolutionMessage.Str(S114, GetUser(), sharedEnvsDir),
+			Code:        123,
+		}
+		return &encErr
+	}
+
+	sharedEnvPath := filepath.Join(sharedEnvsDir, name)
+	if writeErr := ioutil.WriteFile(sharedEnvPath, stripIncludes(data), privateFileMode); writeErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: writeErr,
+			Message:     ErrorCode.Str(E123),
+			Solution:    SolutionMessage.Str(S114, GetUser(), sharedEnvsDir),
+			Code:        123,
+		}
+		return &encErr
+	}
+
+	log.Debug(fmt.Sprintf("Stored shared environment %s", sharedEnvPath))
+	return nil
+}
+
+// RemoveSharedEnv
+// Deletes a shared environment from the global store. The environments that
+// include it fail to render until the include is removed
+func RemoveSharedEnv(name string) *EncapsulatedError {
+	if err := validateSharedEnvName(name); err != nil {
+		return err
+	}
+
+	sharedEnvsDir, err := getSharedEnvsDir()
+	if err != nil {
+		return err
+	}
+
+	sharedEnvPath := filepath.Join(sharedEnvsDir, name)
+	if removeErr := os.Remove(sharedEnvPath); removeErr != nil {
+		if errors.Is(removeErr, os.ErrNotExist) {
+			encErr := EncapsulatedError{
+				OriginalErr: removeErr,
+				Message:     ErrorCode.Str(E124),
+				Solution:    SolutionMessage.Str(S117),
+				Code:        124,
+			}
+			return &encErr
+		}
+
+		encErr := EncapsulatedError{
+			OriginalErr: removeErr,
+			Message:     ErrorCode.Str(E010),
+			Solution:    SolutionMessage.Str(S114, GetUser(), sharedEnvsDir),
+			Code:        10,
+		}
+		return &encErr
+	}
+
+	log.Debug(fmt.Sprintf("Removed shared environment %s", sharedEnvPath))
+	return nil
+}
+
+// AddEnvInclude
+// Makes an environment include a shared environment. The shared environment
+// does not need to exist yet
+func AddEnvInclude(envName string, ref string) *EncapsulatedError {
+	if _, err := parseIncludeRef(ref); err != nil {
+		return err
+	}
+
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	if Contains(meta.Include, ref) {
+		return nil
+	}
+
+	config, err := getInternalConfig()
+	if err != nil {
+		return err
+	}
+
+	dotenvStyle := false
+	for _, trackedFile := range config.TrackedFiles() {
+		dotenvStyle = dotenvStyle || isDotenvStyleFile(trackedFile)
+	}
+
+	if !dotenvStyle {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("%s can not include %s", envName, ref),
+			Message:     ErrorCode.Str(E153),
+			Solution:    SolutionMessage.Str(S146, strings.Join(config.TrackedFiles(), ", ")),
+			Code:        153,
+		}
+		return &encErr
+	}
+
+	meta.Include = append(meta.Include, ref)
+	return setEnvMeta(envName, meta)
+}
+
+// RemoveEnvInclude
+// Stops an environment from including a shared environment
+func RemoveEnvInclude(envName string, ref string) *EncapsulatedError {
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	includes := []string{}
+	for _, existingRef := range meta.Include {
+		if existingRef != ref {
+			includes = append(includes, existingRef)
+		}
+	}
+
+	meta.Include = includes
+	return setEnvMeta(envName, meta)
+}
+
+// dotenvKey
+// Returns the key of a KEY=VALUE line, or an empty string for comments and
+// lines of any other format
+func dotenvKey(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return ""
+	}
+	line = strings.TrimPrefix(line, "export ")
+
+	index := strings.Index(line, "=")
+	if index <= 0 {
+		return ""
+	}
+
+	key := strings.TrimSpace(line[:index])
+	if strings.ContainsAny(key, " \t\"'") {
+		return ""
+	}
+
+	return key
+}
+
+// splitLines
+// Splits the contents of a file to lines without their line endings
+func splitLines(data []byte) []string {
+	text := strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(text) == 0 {
+		return []string{}
+	}
+
+	return strings.Split(text, "\n")
+}
+
+// resolveIncludes
+// Prepends the shared environments that the environment includes to the
+// contents of its stored file. For KEY=VALUE lines the keys of the environment
+// take precedence over the keys of the included environments, and the later
+// includes take precedence over the earlier ones, so every key is written once.
+// JSON and YAML files are left as they are, since the lines would break them
+func resolveIncludes(envName string, fileName string, data []byte) ([]byte, *EncapsulatedError) {
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return data, err
+	}
+
+	if len(meta.Include) == 0 {
+		return data, nil
+	}
+
+	if !isDotenvStyleFile(fileName) {
+		log.Debug(fmt.Sprintf("Not including the shared environments in %s", fileName))
+		return data, nil
+	}
+
+	newline := "\n"
+	if bytes.Contains(data, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+
+	definedKeys := map[string]bool{}
+	for _, line := range splitLines(data) {
+		if key := dotenvKey(line); len(key) > 0 {
+			definedKeys[key] = true
+		}
+	}
+
+	blocks := make([][]string, len(meta.Include))
+	for i := len(meta.Include) - 1; i >= 0; i-- {
+		name, err := parseIncludeRef(meta.Include[i])
+		if err != nil {
+			return data, err
+		}
+
+		sharedData, err := readSharedEnv(name)
+		if err != nil {
+			return data, err
+		}
+
+		blockKeys := []string{}
+		for _, line := range splitLines(stripIncludes(sharedData)) {
+			key := dotenvKey(line)
+			if len(key) > 0 {
+				if definedKeys[key] {
+					log.Debug(fmt.Sprintf("Key %s of %s is overridden", key, meta.Include[i]))
+					continue
+				}
+				blockKeys = append(blockKeys, key)
+			}
+			blocks[i] = append(blocks[i], line)
+		}
+
+		for _, key := range blockKeys {
+			definedKeys[key] = true
+		}
+	}
+
+	var rendered bytes.Buffer
+	for i, ref := range meta.Include {
+		rendered.WriteString(includeBeginMarker + " " + ref + newline)
+		for _, line := range blocks[i] {
+			rendered.WriteString(line + newline)
+		}
+		rendered.WriteString(includeEndMarker + " " + ref + newline)
+	}
+	rendered.Write(data)
+
+	return rendered.Bytes(), nil
+}
+
+// stripIncludes
+// Removes the contents of the included shared environments from a rendered
+// configuration file, so that only the keys of the environment are stored
+func stripIncludes(data []byte) []byte {
+	if !bytes.Contains(data, []byte(includeBeginMarker)) {
+		return data
+	}
+
+	var stripped bytes.Buffer
+	inBlock := false
+
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		trimmedLine := bytes.TrimSpace(line)
+
+		switch {
+		case !inBlock && bytes.HasPrefix(trimmedLine, []byte(includeBeginMarker)):
+			inBlock = true
+		case inBlock && bytes.HasPrefix(trimmedLine, []byte(includeEndMarker)):
+			inBlock = false
+		case !inBlock:
+			stripped.Write(line)
+		}
+	}
+
+	return stripped.Bytes()
+}
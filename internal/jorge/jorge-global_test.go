@@ -0,0 +1,100 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSharedEnvs(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	t.Setenv("XDG_DATA_HOME", filepath.Join(testingRoot, "dataHome"))
+	defer os.RemoveAll(filepath.Join(testingRoot, "dataHome"))
+
+	os.WriteFile(filepath.Join(testingRoot, "sharedConfig"), []byte("STRIPE_KEY=sandbox\n"), 0600)
+	defer os.Remove(filepath.Join(testingRoot, "sharedConfig"))
+
+	if err := AddSharedEnv("../escape", "sharedConfig"); err == nil {
+		t.Fatal("Accepted a shared environment name with a path separator")
+	}
+
+	if err := AddSharedEnv("stripe-sandbox", "sharedConfig"); err != nil {
+		t.Fatal(err)
+	}
+
+	if sharedEnvs, err := ListSharedEnvs(); err != nil {
+		t.Fatal(err)
+	} else if len(sharedEnvs) != 1 || sharedEnvs[0].Name != "stripe-sandbox" {
+		t.Fatalf("Unexpected shared environments %v", sharedEnvs)
+	}
+
+	if err := RemoveSharedEnv("stripe-sandbox"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveSharedEnv("stripe-sandbox"); err == nil || err.Code != 124 {
+		t.Fatalf("Expected code %d, but found %v", 124, err)
+	}
+}
+
+func TestResolveIncludes(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	t.Setenv("XDG_DATA_HOME", filepath.Join(testingRoot, "dataHome"))
+	defer os.RemoveAll(filepath.Join(testingRoot, "dataHome"))
+
+	os.MkdirAll(filepath.Join(testingRoot, "dataHome", "jorge", "shared"), 0700)
+	os.WriteFile(filepath.Join(testingRoot, "dataHome", "jorge", "shared", "first"), []byte("A=first\nB=first\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, "dataHome", "jorge", "shared", "second"), []byte("B=second\nC=second\n"), 0600)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: .env\nextraConfigFiles:\n- app.json\n"), 0600)
+
+	if err := AddEnvInclude("mockEnv", "first"); err == nil {
+		t.Fatal("Accepted an include without the shared/ prefix")
+	}
+
+	AddEnvInclude("mockEnv", "shared/first")
+	AddEnvInclude("mockEnv", "shared/second")
+
+	stored := []byte("C=project\n")
+	rendered, err := resolveIncludes("mockEnv", ".env", stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# >>> jorge include shared/first\nA=first\n# <<< jorge include shared/first\n" +
+		"# >>> jorge include shared/second\nB=second\n# <<< jorge include shared/second\n" +
+		"C=project\n"
+
+	if string(rendered) != expected {
+		t.Fatalf("Expected\n%s\nbut found\n%s", expected, string(rendered))
+	}
+
+	if string(stripIncludes(rendered)) != string(stored) {
+		t.Fatalf("Expected %s after stripping, but found %s", stored, stripIncludes(rendered))
+	}
+
+	// The lines of the shared environments would break structured files
+	if rendered, err := resolveIncludes("mockEnv", "app.json", []byte("{\"C\": \"project\"}\n")); err != nil || string(rendered) != "{\"C\": \"project\"}\n" {
+		t.Fatalf("The includes were added to app.json: %s", rendered)
+	}
+
+	AddEnvInclude("mockEnv", "shared/missing")
+	if _, err := resolveIncludes("mockEnv", ".env", stored); err == nil || err.Code != 124 {
+		t.Fatalf("Expected code %d, but found %v", 124, err)
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: app.json\n"), 0600)
+	if err := AddEnvInclude("mockEnv", "shared/third"); err == nil || err.Code != 153 {
+		t.Fatalf("Expected code %d, but found %v", 153, err)
+	}
+}
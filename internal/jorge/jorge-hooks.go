@@ -0,0 +1,195 @@
+package jorge
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultHookTimeout = 60 * time.Second
+
+// Hook stages that can be configured under the `hooks` key of config.yml
+const (
+	PreUse      = "preUse"
+	PostUse     = "postUse"
+	PreCommit   = "preCommit"
+	PostCommit  = "postCommit"
+	PreRestore  = "preRestore"
+	PostRestore = "postRestore"
+)
+
+type JorgeHooks struct {
+	Timeout     string   `yaml:"timeout,omitempty"`
+	PreUse      []string `yaml:"preUse,omitempty"`
+	PostUse     []string `yaml:"postUse,omitempty"`
+	PreCommit   []string `yaml:"preCommit,omitempty"`
+	PostCommit  []string `yaml:"postCommit,omitempty"`
+	PreRestore  []string `yaml:"preRestore,omitempty"`
+	PostRestore []string `yaml:"postRestore,omitempty"`
+}
+
+var hooksEnabled = true
+
+// SetHooksEnabled
+// Enables or disables the execution of the lifecycle hooks for the current
+// process. It is used by the `--no-hooks` flag
+func SetHooksEnabled(enabled bool) {
+	hooksEnabled = enabled
+}
+
+var commandOutput io.Writer = os.Stdout
+
+// SetCommandOutput
+// Sets where the hooks and the commands of `jorge exec` write their standard
+// output. It is moved to stderr when the results are printed as JSON
+func SetCommandOutput(writer io.Writer) {
+	commandOutput = writer
+}
+
+// commands
+// Returns the list of commands that are configured for the given stage
+func (h JorgeHooks) commands(stage string) []string {
+	switch stage {
+	case PreUse:
+		return h.PreUse
+	case PostUse:
+		return h.PostUse
+	case PreCommit:
+		return h.PreCommit
+	case PostCommit:
+		return h.PostCommit
+	case PreRestore:
+		return h.PreRestore
+	case PostRestore:
+		return h.PostRestore
+	default:
+		return []string{}
+	}
+}
+
+// timeout
+// Returns the maximum duration a single hook command is allowed to run
+func (h JorgeHooks) timeout() time.Duration {
+	if len(h.Timeout) == 0 {
+		return defaultHookTimeout
+	}
+
+	if timeout, err := time.ParseDuration(h.Timeout); err != nil || timeout <= 0 {
+		log.Warn(fmt.Sprintf("Invalid hooks timeout '%s'. Using %v", h.Timeout, defaultHookTimeout))
+		return defaultHookTimeout
+	} else {
+		return timeout
+	}
+}
+
+// runHooks
+// Executes the commands configured for the stage one after the other from the
+// project root. The old and the new environment names are exposed to the
+// commands as JORGE_OLD_ENV and JORGE_NEW_ENV. The first failing command stops
+// the execution
+func runHooks(config JorgeConfig, stage string, oldEnv string, newEnv string) *EncapsulatedError {
+	commands := config.Hooks.commands(stage)
+
+	if !hooksEnabled || len(commands) == 0 {
+		return nil
+	}
+
+	projectRoot, err := resolveJorgeDir()
+	if err != nil {
+		return err
+	}
+
+	timeout := config.Hooks.timeout()
+
+	for _, command := range commands {
+		log.Debug(fmt.Sprintf("Running %s hook '%s'", stage, command))
+
+		hookCmd := hookCommand(command)
+		hookCmd.Dir = projectRoot
+		hookCmd.Stdout = commandOutput
+		hookCmd.Stderr = os.Stderr
+		hookCmd.Env = append(os.Environ(),
+			"JORGE_HOOK="+stage,
+			"JORGE_OLD_ENV="+oldEnv,
+			"JORGE_NEW_ENV="+newEnv,
+			"JORGE_ROOT="+projectRoot,
+			"JORGE_CONFIG_FILE="+config.ConfigFilePath,
+		)
+
+		runErr, timedOut := runWithTimeout(hookCmd, timeout)
+
+		if timedOut {
+			encErr := EncapsulatedError{
+				OriginalErr: fmt.Errorf("hook exceeded the timeout of %v", timeout),
+				Message:     ErrorCode.Str(E114),
+				Solution:    SolutionMessage.Str(S107, command),
+				Code:        114,
+			}
+			return &encErr
+		}
+
+		if runErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: runErr,
+				Message:     ErrorCode.Str(E113),
+				Solution:    SolutionMessage.Str(S107, command),
+				Code:        113,
+			}
+			return &encErr
+		}
+	}
+
+	return nil
+}
+
+// runPostHooks
+// Executes the hooks of a stage that runs after the operation has completed.
+// The operation can not be aborted anymore, so failures are only reported
+func runPostHooks(config JorgeConfig, stage string, oldEnv string, newEnv string) {
+	if err := runHooks(config, stage, oldEnv, newEnv); err != nil {
+		log.Warn(fmt.Sprintf("%s hook failed: %s. %s", stage, err.Message, err.Solution))
+	}
+}
+
+// hookCommand
+// Creates the command that runs a hook through the platform shell
+func hookCommand(command string) *exec.Cmd {
+	var hookCmd *exec.Cmd
+
+	if runtime.GOOS == "windows" {
+		hookCmd = exec.Command("cmd", "/C", command)
+	} else {
+		hookCmd = exec.Command("sh", "-c", command)
+	}
+
+	setProcessGroup(hookCmd)
+	return hookCmd
+}
+
+// runWithTimeout
+// Runs the command and waits for it to exit. When the timeout expires, the
+// command is killed together with any process that it started
+func runWithTimeout(command *exec.Cmd, timeout time.Duration) (error, bool) {
+	if err := command.Start(); err != nil {
+		return err, false
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- command.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err, false
+	case <-time.After(timeout):
+		killProcessGroup(command)
+		<-done
+		return nil, true
+	}
+}
@@ -0,0 +1,99 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunHooksExposesEnvironmentNames(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.Mkdir(filepath.Join(testingRoot, ".jorge"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.Remove(filepath.Join(testingRoot, "hookOutput"))
+
+	config := JorgeConfig{
+		Hooks: JorgeHooks{
+			PostUse: []string{"echo \"$JORGE_OLD_ENV $JORGE_NEW_ENV\" > hookOutput"},
+		},
+	}
+
+	if err := runHooks(config, PostUse, "default", "mockEnv"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if data, err := os.ReadFile(filepath.Join(testingRoot, "hookOutput")); err != nil {
+		t.Log(err)
+		t.FailNow()
+	} else if strings.TrimSpace(string(data)) != "default mockEnv" {
+		t.Logf("Expected %s, but found %s", "default mockEnv", string(data))
+		t.Fail()
+	}
+}
+
+func TestRunHooksTimesOut(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.Mkdir(filepath.Join(testingRoot, ".jorge"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	config := JorgeConfig{
+		Hooks: JorgeHooks{
+			Timeout: "100ms",
+			PreUse:  []string{"sleep 5"},
+		},
+	}
+
+	if err := runHooks(config, PreUse, "default", "mockEnv"); err == nil {
+		t.Fatal("Hook did not time out")
+	} else if err.Code != 114 {
+		t.Fatalf("Expected code %d, but found %d", 114, err.Code)
+	}
+}
+
+func TestFailingPreUseHookAbortsUse(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.Mkdir(filepath.Join(testingRoot, ".jorge"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv"), 0700)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "mainTestConfig"), []byte("mock config contents"), 0600)
+
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: mainTestConfig\nhooks:\n  preUse:\n    - exit 3\n"), 0600); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := UseConfigFile("mockEnv", false); err == nil {
+		t.Fatal("Used the environment although the pre use hook failed")
+	} else if err.Code != 113 {
+		t.Fatalf("Expected code %d, but found %d", 113, err.Code)
+	}
+
+	if config, err := getInternalConfig(); err != nil {
+		t.Fatal(err)
+	} else if config.CurrentEnv != "default" {
+		t.Fatalf("Expected current env %s, but found %s", "default", config.CurrentEnv)
+	}
+
+	SetHooksEnabled(false)
+	defer SetHooksEnabled(true)
+	defer os.Remove(filepath.Join(testingRoot, "mainTestConfig"))
+
+	if _, err := UseConfigFile("mockEnv", false); err != nil {
+		t.Log(err)
+		t.Fatal("Hooks were executed although they were disabled")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package jorge
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup
+// Starts the command in its own process group, so that it can be killed
+// together with its children
+func setProcessGroup(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup
+// Kills the process group of a command that was started with setProcessGroup
+func killProcessGroup(command *exec.Cmd) {
+	if command.Process != nil {
+		syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package jorge
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup
+// Process groups are not used on windows
+func setProcessGroup(command *exec.Cmd) {}
+
+// killProcessGroup
+// Kills the process of the command
+func killProcessGroup(command *exec.Cmd) {
+	if command.Process != nil {
+		command.Process.Kill()
+	}
+}
@@ -0,0 +1,357 @@
+package jorge
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// KeyReport
+// A key of a tracked file and the environments that lack it
+type KeyReport struct {
+	File    string   `json:"file" yaml:"file"`
+	Key     string   `json:"key" yaml:"key"`
+	Missing []string `json:"missing" yaml:"missing"`
+}
+
+// KeysMatrix
+// The keys that are missing from at least one environment
+type KeysMatrix struct {
+	Envs []string    `json:"envs" yaml:"envs"`
+	Keys []KeyReport `json:"keys" yaml:"keys"`
+}
+
+// envFileKeys
+// Returns the keys of the stored file of every environment, indexed by the
+// environment name. The included shared environments are taken into account.
+// Environments whose file can not be parsed are left out
+func envFileKeys(envs []string, fileName string) map[string]map[string]bool {
+	keys := map[string]map[string]bool{}
+
+	for _, env := range envs {
+		data, err := readStoredFile(env, fileName)
+		if err != nil {
+			log.Warn(fmt.Sprintf("Skipping %s of %s: %s", fileName, env, err.Message))
+			continue
+		}
+
+		if renderedData, err := resolveIncludes(env, fileName, data); err == nil {
+			data = renderedData
+		}
+
+		entries, ok := parseConfigEntries(fileName, data)
+		if !ok {
+			log.Warn(fmt.Sprintf("Skipping %s of %s, it can not be parsed", fileName, env))
+			continue
+		}
+
+		keys[env] = map[string]bool{}
+		for _, entry := range entries {
+			keys[env][entry.Key] = true
+		}
+	}
+
+	return keys
+}
+
+// readStoredFile
+// Returns the stored version of a tracked file of an environment
+func readStoredFile(envName string, fileName string) ([]byte, *EncapsulatedError) {
+	envDir, err := getEnvDirPath(envName)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	storedFilePath := filepath.Join(envDir, fileName)
+	data, readErr := ioutil.ReadFile(storedFilePath)
+	if readErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E107),
+			Solution:    SolutionMessage.Str(S003, storedFilePath),
+			Code:        107,
+		}
+		return []byte{}, &encErr
+	}
+
+	return data, nil
+}
+
+// CheckKeys
+// Builds the union of the keys of every environment, per tracked file, and
+// returns the keys that some environments lack
+func CheckKeys() (KeysMatrix, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return KeysMatrix{}, err
+	}
+
+	envs, err := getEnvs()
+	if err != nil {
+		return KeysMatrix{}, err
+	}
+	sort.Strings(envs)
+
+	matrix := KeysMatrix{Envs: envs, Keys: []KeyReport{}}
+
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+		keysByEnv := envFileKeys(envs, trackedFileName)
+
+		union := map[string]bool{}
+		for _, keys := range keysByEnv {
+			for key := range keys {
+				union[key] = true
+			}
+		}
+
+		sortedKeys := []string{}
+		for key := range union {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+
+		for _, key := range sortedKeys {
+			missing := []string{}
+			for _, env := range envs {
+				if keys, parsed := keysByEnv[env]; parsed && !keys[key] {
+					missing = append(missing, env)
+				}
+			}
+
+			if len(missing) > 0 {
+				matrix.Keys = append(matrix.Keys, KeyReport{File: trackedFileName, Key: key, Missing: missing})
+			}
+		}
+	}
+
+	return matrix, nil
+}
+
+// FixMissingKeys
+// Copies the keys that the other environments lack from the source
+// environment. When usePlaceholder is set, the placeholder is written instead
+// of the value of the source environment. It returns the keys that were added
+func FixMissingKeys(sourceEnv string, placeholder string, usePlaceholder bool) ([]KeyReport, *EncapsulatedError) {
+	if _, err := getEnvDirPath(sourceEnv); err != nil {
+		return []KeyReport{}, err
+	}
+
+	matrix, err := CheckKeys()
+	if err != nil {
+		return []KeyReport{}, err
+	}
+
+	sourceFiles := map[string][]byte{}
+	fixed := []KeyReport{}
+
+	for _, report := range matrix.Keys {
+		if Contains(report.Missing, sourceEnv) {
+			continue
+		}
+
+		sourceData, found := sourceFiles[report.File]
+		if !found {
+			data, err := readStoredFile(sourceEnv, report.File)
+			if err != nil {
+				return fixed, err
+			}
+
+			if renderedData, err := resolveIncludes(sourceEnv, report.File, data); err == nil {
+				data = renderedData
+			}
+			sourceData = data
+			sourceFiles[report.File] = data
+		}
+
+		fixedReport := KeyReport{File: report.File, Key: report.Key, Missing: []string{}}
+
+		for _, env := range report.Missing {
+			data, err := readStoredFile(env, report.File)
+			if err != nil {
+				return fixed, err
+			}
+
+			fixedData, addErr := addConfigKey(report.File, data, sourceData, report.Key, placeholder, usePlaceholder)
+			if addErr != nil {
+				log.Warn(fmt.Sprintf("Could not add %s to %s of %s: %v", report.Key, report.File, env, addErr))
+				continue
+			}
+
+			envDir, _ := getEnvDirPath(env)
+			if writeErr := ioutil.WriteFile(filepath.Join(envDir, report.File), fixedData, privateFileMode); writeErr != nil {
+				encErr := EncapsulatedError{
+					OriginalErr: writeErr,
+					Message:     ErrorCode.Str(E110),
+					Solution:    SolutionMessage.Str(S103, GetUser()),
+					Code:        110,
+				}
+				return fixed, &encErr
+			}
+
+			if err := touchEnvMeta(env, false); err != nil {
+				log.Warn(fmt.Sprintf("%s: %s", err.Message, err.OriginalErr))
+			}
+			fixedReport.Missing = append(fixedReport.Missing, env)
+		}
+
+		if len(fixedReport.Missing) > 0 {
+			fixed = append(fixed, fixedReport)
+		}
+	}
+
+	return fixed, nil
+}
+
+// addConfigKey
+// Adds a key of the source file to a configuration file. KEY=VALUE files get
+// the line of the source file appended, while JSON and YAML files are decoded,
+// updated and encoded again, keeping the order of the keys and the numbers as
+// they were written
+func addConfigKey(fileName string, data []byte, sourceData []byte, key string, placeholder string, usePlaceholder bool) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		source, err := decodeOrderedJSON(sourceData)
+		if err != nil {
+			return data, err
+		}
+		document, err := decodeOrderedJSON(data)
+		if err != nil {
+			return data, err
+		}
+
+		var value interface{} = placeholder
+		if !usePlaceholder {
+			var found bool
+			if value, found = lookupConfigKey(source, strings.Split(key, ".")); !found {
+				return data, fmt.Errorf("%s can not be found in the source file", key)
+			}
+		}
+
+		document, err = setConfigKey(document, strings.Split(key, "."), value)
+		if err != nil {
+			return data, err
+		}
+
+		encoded, err := encodeOrderedJSON(document)
+		return matchLineEndings(data, encoded), err
+	case ".yml", ".yaml":
+		var source, document yaml.MapSlice
+		if err := yaml.Unmarshal(sourceData, &source); err != nil {
+			return data, err
+		}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return data, err
+		}
+
+		var value interface{} = placeholder
+		if !usePlaceholder {
+			var found bool
+			if value, found = lookupConfigKey(source, strings.Split(key, ".")); !found {
+				return data, fmt.Errorf("%s can not be found in the source file", key)
+			}
+		}
+
+		updated, err := setConfigKey(document, strings.Split(key, "."), value)
+		if err != nil {
+			return data, err
+		}
+
+		encoded, err := yaml.Marshal(updated)
+		return matchLineEndings(data, encoded), err
+	default:
+		line := key + "=" + placeholder
+		if !usePlaceholder {
+			found := false
+			for _, sourceLine := range splitLines(sourceData) {
+				if dotenvKey(sourceLine) == key {
+					line, found = strings.TrimSpace(sourceLine), true
+				}
+			}
+
+			if !found {
+				return data, fmt.Errorf("%s can not be found in the source file", key)
+			}
+		}
+
+		newline := "\n"
+		if bytes.Contains(data, []byte("\r\n")) {
+			newline = "\r\n"
+		}
+
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, newline...)
+		}
+
+		return append(data, line+newline...), nil
+	}
+}
+
+// lookupConfigKey
+// Returns the value found under the path of a decoded JSON or YAML document
+func lookupConfigKey(document interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return document, true
+	}
+
+	switch typedDocument := document.(type) {
+	case map[string]interface{}:
+		if value, found := typedDocument[path[0]]; found {
+			return lookupConfigKey(value, path[1:])
+		}
+	case yaml.MapSlice:
+		for _, item := range typedDocument {
+			if fmt.Sprint(item.Key) == path[0] {
+				return lookupConfigKey(item.Value, path[1:])
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// setConfigKey
+// Sets the value under the path of a decoded JSON or YAML document, creating
+// the missing levels
+func setConfigKey(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	switch typedDocument := document.(type) {
+	case nil:
+		return setConfigKey(map[string]interface{}{}, path, value)
+	case map[string]interface{}:
+		nestedValue, err := setConfigKey(typedDocument[path[0]], path[1:], value)
+		if err != nil {
+			return document, err
+		}
+		typedDocument[path[0]] = nestedValue
+		return typedDocument, nil
+	case yaml.MapSlice:
+		for i, item := range typedDocument {
+			if fmt.Sprint(item.Key) == path[0] {
+				nestedValue, err := setConfigKey(item.Value, path[1:], value)
+				if err != nil {
+					return document, err
+				}
+				typedDocument[i].Value = nestedValue
+				return typedDocument, nil
+			}
+		}
+
+		nestedValue, err := setConfigKey(yaml.MapSlice{}, path[1:], value)
+		if err != nil {
+			return document, err
+		}
+		return append(typedDocument, yaml.MapItem{Key: path[0], Value: nestedValue}), nil
+	default:
+		return document, fmt.Errorf("%s is not an object", path[0])
+	}
+}
@@ -0,0 +1,80 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckAndFixKeys(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "default"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "staging"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env"), []byte("HOST=localhost\nFEATURE_FLAG_X=\"on\"\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env"), []byte("HOST=staging"), 0600)
+
+	matrix, err := CheckKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matrix.Keys) != 1 || matrix.Keys[0].Key != "FEATURE_FLAG_X" || len(matrix.Keys[0].Missing) != 1 || matrix.Keys[0].Missing[0] != "staging" {
+		t.Fatalf("Unexpected missing keys %v", matrix.Keys)
+	}
+
+	if _, err := FixMissingKeys("default", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env")); string(data) != "HOST=staging\nFEATURE_FLAG_X=\"on\"\n" {
+		t.Fatalf("Unexpected contents %s", data)
+	}
+
+	if matrix, err := CheckKeys(); err != nil || len(matrix.Keys) != 0 {
+		t.Fatalf("Unexpected missing keys %v (%v)", matrix.Keys, err)
+	}
+}
+
+func TestAddConfigKeyToYaml(t *testing.T) {
+	source := []byte("database:\n  host: localhost\n  port: 5432\n")
+	target := []byte("database:\n  host: staging\n")
+
+	data, err := addConfigKey("application.yml", target, source, "database.port", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "database:\n  host: staging\n  port: 5432\n" {
+		t.Fatalf("Unexpected contents %s", data)
+	}
+
+	data, err = addConfigKey("application.yml", target, source, "cache.url", "CHANGE_ME", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "database:\n  host: staging\ncache:\n  url: CHANGE_ME\n" {
+		t.Fatalf("Unexpected contents %s", data)
+	}
+}
+
+func TestAddConfigKeyToJson(t *testing.T) {
+	source := []byte("{\"id\": 1, \"port\": 5432}")
+	target := []byte("{\n  \"zone\": \"eu\",\n  \"id\": 12345678901234567890\n}\n")
+
+	data, err := addConfigKey("app.json", target, source, "port", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "{\n  \"zone\": \"eu\",\n  \"id\": 12345678901234567890,\n  \"port\": 5432\n}\n" {
+		t.Fatalf("Unexpected contents %s", data)
+	}
+}
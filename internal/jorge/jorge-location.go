@@ -0,0 +1,387 @@
+package jorge
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const jorgeRcFileName = ".jorgerc"
+const projectsDirName = "projects"
+
+// The rules that stop the search for the project root. By default the search
+// continues up to the root of the filesystem
+const (
+	StopAtGit        = "git"
+	StopAtFilesystem = "filesystem"
+)
+
+// The places that the location of the store can come from
+const (
+	StoreFromEnv     = "JORGE_DIR"
+	StoreFromJorgeRc = jorgeRcFileName
+	StoreFromDefault = "default"
+)
+
+var projectDirOverride string
+var stopAtOverride string
+
+// JorgeRc
+// A .jorgerc file at the project root, which points to a store that lives
+// outside of the project
+type JorgeRc struct {
+	Dir string `yaml:"dir"`
+}
+
+// ProjectLocation
+// The resolved paths of a jorge project
+type ProjectLocation struct {
+	Root    string `json:"root" yaml:"root"`
+	Store   string `json:"store" yaml:"store"`
+	Source  string `json:"source" yaml:"source"`
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// SetProjectDir
+// Makes jorge use the named project of the monorepo, or the project at the
+// path, instead of searching for it from the current directory. An empty
+// value restores the search
+func SetProjectDir(path string) {
+	projectDirOverride = path
+}
+
+// SetStopAt
+// Sets the rule that stops the search for the project root. An empty value
+// falls back to $JORGE_STOP_AT
+func SetStopAt(rule string) {
+	stopAtOverride = rule
+}
+
+// expandPath
+// Expands a leading ~ to the home directory of the user and resolves relative
+// paths against base
+func expandPath(path string, base string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+
+	return filepath.Abs(path)
+}
+
+// readJorgeRc
+// Reads the .jorgerc file of a directory. The second return value is false
+// when the directory has none
+func readJorgeRc(dir string) (JorgeRc, bool, *EncapsulatedError) {
+	rcPath := filepath.Join(dir, jorgeRcFileName)
+	data, readErr := ioutil.ReadFile(rcPath)
+	if errors.Is(readErr, os.ErrNotExist) {
+		return JorgeRc{}, false, nil
+	}
+
+	var rc JorgeRc
+	if readErr == nil {
+		readErr = yaml.Unmarshal(data, &rc)
+	}
+	if readErr == nil && len(rc.Dir) == 0 {
+		readErr = errors.New("dir is not set")
+	}
+
+	if readErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E146),
+			Solution:    SolutionMessage.Str(S139, rcPath),
+			Code:        146,
+		}
+		return JorgeRc{}, true, &encErr
+	}
+
+	return rc, true, nil
+}
+
+// getStoreLocation
+// Returns the path of the store of the project at root and where the path
+// came from. $JORGE_DIR takes precedence over the .jorgerc file, and both over
+// the .jorge directory at the root
+func getStoreLocation(root string) (string, string, *EncapsulatedError) {
+	if envDir := os.Getenv("JORGE_DIR"); len(envDir) > 0 {
+		cwd, _ := os.Getwd()
+		store, err := expandPath(envDir, cwd)
+		return store, StoreFromEnv, locationError(err)
+	}
+
+	rc, found, err := readJorgeRc(root)
+	if err != nil {
+		return "", "", err
+	}
+
+	if found {
+		store, expandErr := expandPath(rc.Dir, root)
+		return store, StoreFromJorgeRc, locationError(expandErr)
+	}
+
+	return filepath.Join(root, jorgeConfigDir), StoreFromDefault, nil
+}
+
+// locationError
+// Wraps an error that occurred while resolving a path
+func locationError(err error) *EncapsulatedError {
+	if err == nil {
+		return nil
+	}
+
+	encErr := EncapsulatedError{
+		OriginalErr: err,
+		Message:     ErrorCode.Str(E000),
+		Solution:    SolutionMessage.Str(S000),
+		Code:        1,
+	}
+	return &encErr
+}
+
+// isProjectDir
+// Determines whether the directory is the root of a jorge project
+func isProjectDir(dir string) bool {
+	if hasJorgeDir(dir) {
+		return true
+	}
+
+	_, err := os.Stat(filepath.Join(dir, jorgeRcFileName))
+	return err == nil
+}
+
+// findProjectRoot
+// Searches the directory and its ancestors for the root of a jorge project,
+// until the rule of SetStopAt or $JORGE_STOP_AT stops the search. The second
+// return value is false when no project was found
+func findProjectRoot(dir string) (string, bool, *EncapsulatedError) {
+	stopAt := stopAtOverride
+	if len(stopAt) == 0 {
+		stopAt = os.Getenv("JORGE_STOP_AT")
+	}
+
+	if stopAt != "" && stopAt != StopAtGit && stopAt != StopAtFilesystem {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("the search for the project stops at %s", stopAt),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S109, stopAt, strings.Join([]string{StopAtGit, StopAtFilesystem}, ", ")),
+			Code:        118,
+		}
+		return "", false, &encErr
+	}
+
+	for {
+		if isProjectDir(dir) {
+			return dir, true, nil
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil && stopAt == StopAtGit {
+			log.Debug(fmt.Sprintf("Stopped the search at the git root %s", dir))
+			return "", false, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false, nil
+		}
+
+		if stopAt == StopAtFilesystem && !onSameFilesystem(dir, parent) {
+			log.Debug(fmt.Sprintf("Stopped the search at the filesystem boundary %s", dir))
+			return "", false, nil
+		}
+
+		dir = parent
+	}
+}
+
+// readProjectNames
+// Returns the names of the projects that the store at the root of a monorepo
+// hosts
+func readProjectNames(store string) []string {
+	data, err := ioutil.ReadFile(filepath.Join(store, configFileName))
+	if err != nil {
+		return []string{}
+	}
+
+	var config JorgeConfig
+	if yaml.Unmarshal(data, &config) != nil {
+		return []string{}
+	}
+
+	return config.Projects
+}
+
+// getRecordedRoot
+// Returns the root of the project that a store outside of it was initialized
+// for, when the directory is within it. A store that was not initialized yet
+// belongs to the directory, which becomes the root of the project
+func getRecordedRoot(store string, dir string) (string, *EncapsulatedError) {
+	data, readErr := ioutil.ReadFile(filepath.Join(store, configFileName))
+	if errors.Is(readErr, os.ErrNotExist) {
+		return dir, nil
+	}
+
+	var config JorgeConfig
+	if readErr == nil {
+		readErr = yaml.Unmarshal(data, &config)
+	}
+	if readErr == nil && len(config.Root) == 0 {
+		readErr = errors.New("the store does not record the root of its project")
+	}
+
+	if readErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E154),
+			Solution:    SolutionMessage.Str(S147, store),
+			Code:        154,
+		}
+		return "", &encErr
+	}
+
+	if !isWithinPath(config.Root, dir) {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("%s is not within %s", dir, config.Root),
+			Message:     ErrorCode.Str(E154),
+			Solution:    SolutionMessage.Str(S148, store, config.Root, config.Root),
+			Code:        154,
+		}
+		return "", &encErr
+	}
+
+	return config.Root, nil
+}
+
+// selectProject
+// Returns the project of a monorepo that contains the directory. The project
+// with the longest name wins, so that nested projects are selected
+func selectProject(root string, projects []string, dir string) (string, bool) {
+	selected, found := "", false
+	for _, project := range projects {
+		projectRoot := filepath.Join(root, filepath.FromSlash(project))
+		if relDir, err := filepath.Rel(projectRoot, dir); err != nil || relDir == ".." || strings.HasPrefix(relDir, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		if len(project) > len(selected) {
+			selected, found = project, true
+		}
+	}
+
+	return selected, found
+}
+
+// namedProjectLocation
+// Returns the location of a named project of the monorepo whose root and store
+// are given
+func namedProjectLocation(root string, store string, source string, project string) ProjectLocation {
+	return ProjectLocation{
+		Root:    filepath.Join(root, filepath.FromSlash(project)),
+		Store:   filepath.Join(store, projectsDirName, filepath.FromSlash(project)),
+		Source:  source,
+		Project: project,
+	}
+}
+
+// locateProject
+// Resolves the root and the store of the project that commands act on. The
+// project that was set with SetProjectDir is a name of a project of the
+// monorepo or the path to a project root. Otherwise the project that contains
+// the current directory is used, which is the named project of the monorepo
+// that contains it, if any
+func locateProject() (ProjectLocation, *EncapsulatedError) {
+	currentAbsPath, err := filepath.Abs(".")
+	if err != nil {
+		return ProjectLocation{}, locationError(err)
+	}
+
+	root, found, encErr := findProjectRoot(currentAbsPath)
+	if encErr != nil {
+		return ProjectLocation{}, encErr
+	}
+
+	var store, source string
+	if found {
+		if store, source, encErr = getStoreLocation(root); encErr != nil {
+			return ProjectLocation{}, encErr
+		}
+	}
+
+	if len(projectDirOverride) > 0 {
+		if found && Contains(readProjectNames(store), filepath.ToSlash(projectDirOverride)) {
+			return namedProjectLocation(root, store, source, filepath.ToSlash(projectDirOverride)), nil
+		}
+
+		overrideRoot, absErr := filepath.Abs(projectDirOverride)
+		if absErr != nil {
+			return ProjectLocation{}, locationError(absErr)
+		}
+
+		overrideStore, overrideSource, err := getStoreLocation(overrideRoot)
+		return ProjectLocation{Root: overrideRoot, Store: overrideStore, Source: overrideSource}, err
+	}
+
+	if found {
+		log.Debug("Resolve jorge dir at ", root)
+		if project, inProject := selectProject(root, readProjectNames(store), currentAbsPath); inProject {
+			log.Debug(fmt.Sprintf("Selected the project %s", project))
+			return namedProjectLocation(root, store, source, project), nil
+		}
+
+		return ProjectLocation{Root: root, Store: store, Source: source}, nil
+	}
+
+	// A store that is set with $JORGE_DIR belongs to the project it recorded
+	if store, source, _ := getStoreLocation(currentAbsPath); source == StoreFromEnv {
+		if info, statErr := os.Stat(store); statErr == nil && info.IsDir() {
+			root, err := getRecordedRoot(store, currentAbsPath)
+			if err != nil {
+				return ProjectLocation{}, err
+			}
+
+			log.Debug("Resolve jorge dir at ", root)
+			return ProjectLocation{Root: root, Store: store, Source: source}, nil
+		}
+	}
+
+	encErr = &EncapsulatedError{
+		OriginalErr: ErrorCode.Err(E100),
+		Message:     ErrorCode.Str(E100),
+		Solution:    SolutionMessage.Str(S100),
+		Code:        100,
+	}
+	return ProjectLocation{}, encErr
+}
+
+// Where
+// Returns the root and the store of the current project
+func Where() (ProjectLocation, *EncapsulatedError) {
+	return locateProject()
+}
+
+// getStoreIgnorePath
+// Returns the path of the store relative to the project root, as it is
+// written to .gitignore. The second return value is false when the store
+// lives outside of the project
+func getStoreIgnorePath(root string, store string) (string, bool) {
+	relStore, err := filepath.Rel(root, store)
+	if err != nil || relStore == ".." || strings.HasPrefix(relStore, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return filepath.ToSlash(relStore), true
+}
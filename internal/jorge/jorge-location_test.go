@@ -0,0 +1,85 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProjectLocation(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, "store"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, "level01", ".git"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, "store"))
+	defer os.RemoveAll(filepath.Join(testingRoot, "level01"))
+	os.WriteFile(filepath.Join(testingRoot, jorgeRcFileName), []byte("dir: store\n"), 0600)
+	defer os.Remove(filepath.Join(testingRoot, jorgeRcFileName))
+
+	os.Chdir(filepath.Join(testingRoot, "level01"))
+
+	location, err := Where()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if location.Root != testingRoot || location.Store != filepath.Join(testingRoot, "store") || location.Source != StoreFromJorgeRc {
+		t.Fatalf("Unexpected location %v", location)
+	}
+
+	t.Setenv("JORGE_STOP_AT", StopAtGit)
+	if _, err := resolveJorgeDir(); err == nil || err.Code != 100 {
+		t.Fatalf("Expected code %d, but found %v", 100, err)
+	}
+
+	SetProjectDir(testingRoot)
+	defer SetProjectDir("")
+
+	if jorgeDir, err := getJorgeDir(); err != nil || jorgeDir != filepath.Join(testingRoot, "store") {
+		t.Fatalf("Unexpected store %s (%v)", jorgeDir, err)
+	}
+
+	t.Setenv("JORGE_DIR", filepath.Join(testingRoot, "level01"))
+	if location, err := Where(); err != nil || location.Store != filepath.Join(testingRoot, "level01") || location.Source != StoreFromEnv {
+		t.Fatalf("Unexpected location %v (%v)", location, err)
+	}
+}
+
+func TestStoreFromEnvBelongsToRecordedRoot(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, "app", "sub"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, "other"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, "store"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, "app"))
+	defer os.RemoveAll(filepath.Join(testingRoot, "other"))
+	defer os.RemoveAll(filepath.Join(testingRoot, "store"))
+
+	os.WriteFile(filepath.Join(testingRoot, "store", configFileName), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\nroot: "+filepath.Join(testingRoot, "app")+"\n"), 0600)
+	t.Setenv("JORGE_DIR", filepath.Join(testingRoot, "store"))
+
+	os.Chdir(filepath.Join(testingRoot, "app", "sub"))
+	if location, err := Where(); err != nil || location.Root != filepath.Join(testingRoot, "app") || location.Source != StoreFromEnv {
+		t.Fatalf("Unexpected location %v (%v)", location, err)
+	}
+
+	os.Chdir(filepath.Join(testingRoot, "other"))
+	if _, err := Where(); err == nil || err.Code != 154 {
+		t.Fatalf("Expected code %d, but found %v", 154, err)
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, "store", configFileName), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\n"), 0600)
+	if _, err := Where(); err == nil || err.Code != 154 {
+		t.Fatalf("Expected code %d, but found %v", 154, err)
+	}
+
+	SetStopAt("nowhere")
+	defer SetStopAt("")
+	if _, err := Where(); err == nil || err.Code != 118 {
+		t.Fatalf("Expected code %d, but found %v", 118, err)
+	}
+}
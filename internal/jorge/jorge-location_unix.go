@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package jorge
+
+import (
+	"os"
+	"syscall"
+)
+
+// onSameFilesystem
+// Determines whether two directories are on the same device
+func onSameFilesystem(a string, b string) bool {
+	aInfo, aErr := os.Stat(a)
+	bInfo, bErr := os.Stat(b)
+	if aErr != nil || bErr != nil {
+		return false
+	}
+
+	aStat, aOk := aInfo.Sys().(*syscall.Stat_t)
+	bStat, bOk := bInfo.Sys().(*syscall.Stat_t)
+
+	return aOk && bOk && aStat.Dev == bStat.Dev
+}
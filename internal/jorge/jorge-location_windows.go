@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package jorge
+
+import "path/filepath"
+
+// onSameFilesystem
+// Determines whether two directories are on the same volume
+func onSameFilesystem(a string, b string) bool {
+	return filepath.VolumeName(a) == filepath.VolumeName(b)
+}
@@ -0,0 +1,170 @@
+package jorge
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// materializeError
+// Wraps an error that occurred while writing a materialized file
+func materializeError(err error, target string) *EncapsulatedError {
+	encErr := EncapsulatedError{
+		OriginalErr: err,
+		Message:     ErrorCode.Str(E144),
+		Solution:    SolutionMessage.Str(S136, GetUser(), target),
+		Code:        144,
+	}
+	return &encErr
+}
+
+// writeMaterializedFile
+// Writes a file with the given mode. The file is written next to its target
+// and renamed over it, so that readers never see a partial file
+func writeMaterializedFile(target string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(target), "."+filepath.Base(target)+".jorge-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempFile.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), target)
+	}
+
+	return err
+}
+
+// Materialize
+// Writes the rendered files of an environment to another location than the
+// tracked files, without using the environment. An empty file name selects
+// every tracked file. When a single file is written, to is the path of the
+// file, unless it is an existing directory. Several files are written to the
+// directory to, by their names. The path - writes a single file to stdout. An
+// empty environment name selects the current environment. A mode of 0 selects
+// the mode that applies to the working files, or 0600. It returns the paths
+// that were written
+func Materialize(envName string, fileName string, to string, mode os.FileMode, stdout io.Writer) ([]string, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return []string{}, err
+	}
+
+	projectRoot, err := resolveJorgeDir()
+	if err != nil {
+		return []string{}, err
+	}
+
+	if len(envName) == 0 {
+		envName = config.CurrentEnv
+	}
+
+	if _, err := getEnvDirPath(envName); err != nil {
+		return []string{}, err
+	}
+
+	trackedFileNames := []string{}
+	selectedFileNames := []string{}
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+		trackedFileNames = append(trackedFileNames, trackedFileName)
+
+		if len(fileName) == 0 || fileName == trackedFileName || fileName == trackedFile {
+			selectedFileNames = append(selectedFileNames, trackedFileName)
+		}
+	}
+
+	if len(selectedFileNames) == 0 || (to == "-" && len(selectedFileNames) > 1) {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("%s does not select a single tracked file", fileName),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S126, strings.Join(trackedFileNames, ", ")),
+			Code:        118,
+		}
+		return []string{}, &encErr
+	}
+
+	intoDir := len(selectedFileNames) > 1
+	if info, statErr := os.Stat(to); statErr == nil && info.IsDir() {
+		intoDir = true
+	}
+
+	written := []string{}
+	for _, selectedFileName := range selectedFileNames {
+		storedData, err := readStoredFile(envName, selectedFileName)
+		if err != nil {
+			return written, err
+		}
+
+		renderedData, _, err := renderConfigFile(envName, selectedFileName, storedData)
+		if err != nil {
+			return written, err
+		}
+
+		if to == "-" {
+			if _, writeErr := stdout.Write(renderedData); writeErr != nil {
+				return written, materializeError(writeErr, to)
+			}
+			written = append(written, to)
+			continue
+		}
+
+		target := to
+		if intoDir {
+			target = filepath.Join(to, selectedFileName)
+		}
+
+		absTarget, absErr := filepath.Abs(target)
+		if absErr != nil {
+			return written, materializeError(absErr, target)
+		}
+
+		// Writing over a working file would switch the environment behind the
+		// back of config.yml
+		for _, trackedFile := range config.TrackedFiles() {
+			if absTarget == filepath.Join(projectRoot, trackedFile) {
+				encErr := EncapsulatedError{
+					OriginalErr: fmt.Errorf("%s is tracked", target),
+					Message:     ErrorCode.Str(E144),
+					Solution:    SolutionMessage.Str(S137, target, envName),
+					Code:        144,
+				}
+				return written, &encErr
+			}
+		}
+
+		fileMode := mode
+		if fileMode == 0 {
+			if fileMode, err = getRecordedFileMode(config, envName, selectedFileName); err != nil {
+				return written, err
+			} else if fileMode == 0 {
+				fileMode = privateFileMode
+			}
+		}
+
+		if writeErr := writeMaterializedFile(absTarget, renderedData, fileMode); writeErr != nil {
+			return written, materializeError(writeErr, target)
+		}
+
+		log.Debug(fmt.Sprintf("Materialized %s of %s to %s", selectedFileName, envName, target))
+		written = append(written, target)
+	}
+
+	return written, nil
+}
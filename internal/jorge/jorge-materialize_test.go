@@ -0,0 +1,50 @@
+package jorge
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaterialize(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "default"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "staging"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.RemoveAll(filepath.Join(testingRoot, "build"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\nextraConfigFiles:\n- app.json\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env"), []byte("HOST=staging\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", "app.json"), []byte("{}\n"), 0600)
+
+	if written, err := Materialize("staging", "", "build", 0640, nil); err != nil || len(written) != 2 {
+		t.Fatalf("Unexpected files %v (%v)", written, err)
+	}
+
+	if info, err := os.Stat(filepath.Join(testingRoot, "build", ".env")); err != nil || info.Mode().Perm() != 0640 {
+		t.Fatalf("Unexpected materialized file %v (%v)", info, err)
+	}
+
+	var stdout bytes.Buffer
+	if _, err := Materialize("staging", ".env", "-", 0600, &stdout); err != nil || stdout.String() != "HOST=staging\n" {
+		t.Fatalf("Unexpected output %q (%v)", stdout.String(), err)
+	}
+
+	if _, err := Materialize("staging", "", "-", 0600, &stdout); err == nil || err.Code != 118 {
+		t.Fatalf("Expected code %d, but found %v", 118, err)
+	}
+
+	if _, err := Materialize("staging", ".env", ".env", 0600, nil); err == nil || err.Code != 144 {
+		t.Fatalf("Expected code %d, but found %v", 144, err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, ".jorge", "config.yml")); !strings.Contains(string(data), "currentEnv: default") {
+		t.Fatal("Materialize changed the current environment")
+	}
+}
@@ -0,0 +1,701 @@
+package jorge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const baseDirName = ".base"
+const baseMetaFileName = "base.yml"
+
+const conflictOursMarker = "<<<<<<< "
+const conflictSeparator = "======="
+const conflictTheirsMarker = ">>>>>>> "
+
+// workingCopyBase
+// The revision of an environment that the working configuration files were
+// created from. A copy of its stored files is kept next to it, so that a
+// commit can tell the changes of the working copy from the changes that were
+// stored in the meantime. Conflicts lists the files of a commit that stopped
+// on conflicts
+type workingCopyBase struct {
+	Revision  int      `yaml:"revision"`
+	Conflicts []string `yaml:"conflicts,omitempty"`
+}
+
+// getBaseDir
+// Returns the path to the directory that holds the base of the working copy
+// of an environment
+func getBaseDir(envName string) (string, *EncapsulatedError) {
+	envDir, err := getEnvDirPath(envName)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(envDir, baseDirName), nil
+}
+
+// baseError
+// Wraps a filesystem error of the base of the working copy
+func baseError(err error) *EncapsulatedError {
+	encErr := EncapsulatedError{
+		OriginalErr: err,
+		Message:     ErrorCode.Str(E136),
+		Solution:    SolutionMessage.Str(S103, GetUser()),
+		Code:        136,
+	}
+	return &encErr
+}
+
+// getWorkingCopyBase
+// Returns the base of the working copy of an environment. The second return
+// value is false when no base was recorded
+func getWorkingCopyBase(envName string) (workingCopyBase, bool, *EncapsulatedError) {
+	baseDir, err := getBaseDir(envName)
+	if err != nil {
+		return workingCopyBase{}, false, err
+	}
+
+	data, readErr := ioutil.ReadFile(filepath.Join(baseDir, baseMetaFileName))
+	if errors.Is(readErr, os.ErrNotExist) {
+		return workingCopyBase{}, false, nil
+	} else if readErr != nil {
+		return workingCopyBase{}, false, baseError(readErr)
+	}
+
+	var base workingCopyBase
+	if ymlErr := yaml.Unmarshal(data, &base); ymlErr != nil {
+		return workingCopyBase{}, false, baseError(ymlErr)
+	}
+
+	return base, true, nil
+}
+
+// recordWorkingCopyBase
+// Records the stored files of an environment as the base of its working copy.
+// Like the revision itself, the base is only recorded for environments with
+// metadata
+func recordWorkingCopyBase(config JorgeConfig, envName string, conflicts []string) *EncapsulatedError {
+	envDir, err := getEnvDirPath(envName)
+	if err != nil {
+		return err
+	}
+
+	if _, statErr := os.Stat(filepath.Join(envDir, envMetaFileName)); statErr != nil {
+		return nil
+	}
+
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	baseDir := filepath.Join(envDir, baseDirName)
+	if removeErr := os.RemoveAll(baseDir); removeErr != nil {
+		return baseError(removeErr)
+	}
+	if mkdirErr := os.Mkdir(baseDir, privateDirMode); mkdirErr != nil {
+		return baseError(mkdirErr)
+	}
+
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+
+		data, readErr := ioutil.ReadFile(filepath.Join(envDir, trackedFileName))
+		if errors.Is(readErr, os.ErrNotExist) {
+			continue
+		} else if readErr != nil {
+			return baseError(readErr)
+		}
+
+		if writeErr := ioutil.WriteFile(filepath.Join(baseDir, trackedFileName), data, privateFileMode); writeErr != nil {
+			return baseError(writeErr)
+		}
+	}
+
+	data, ymlErr := yaml.Marshal(&workingCopyBase{Revision: meta.Revision, Conflicts: conflicts})
+	if ymlErr == nil {
+		ymlErr = ioutil.WriteFile(filepath.Join(baseDir, baseMetaFileName), data, privateFileMode)
+	}
+	if ymlErr != nil {
+		return baseError(ymlErr)
+	}
+
+	log.Debug(fmt.Sprintf("Recorded revision %d of %s as the base of the working copy", meta.Revision, envName))
+	return nil
+}
+
+// removeWorkingCopyBase
+// Forgets the base of the working copy of an environment
+func removeWorkingCopyBase(envName string) {
+	if baseDir, err := getBaseDir(envName); err == nil {
+		os.RemoveAll(baseDir)
+	}
+}
+
+// hasConflictMarkers
+// Determines whether the contents of a file hold conflict markers
+func hasConflictMarkers(data []byte) bool {
+	for _, line := range splitLines(data) {
+		if strings.HasPrefix(line, conflictOursMarker) || strings.HasPrefix(line, conflictTheirsMarker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeWorkingFile
+// Renders the contents of a stored file of an environment to a working
+// configuration file and applies the recorded attributes of the file
+func writeWorkingFile(path string, config JorgeConfig, envName string, data []byte) *EncapsulatedError {
+	_, fileName := filepath.Split(path)
+
+	renderedData, secretRefs, err := renderConfigFile(envName, fileName, data)
+	if err != nil {
+		return err
+	}
+
+	writeErr := removeSymlink(path)
+	if writeErr == nil {
+		writeErr = ioutil.WriteFile(path, renderedData, privateFileMode)
+	}
+
+	if writeErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: writeErr,
+			Message:     ErrorCode.Str(E007),
+			Solution:    SolutionMessage.Str(S002, GetUser()),
+			Code:        7,
+		}
+		return &encErr
+	}
+
+	if err := applyFileAttributes(path, config, envName, fileName); err != nil {
+		return err
+	}
+
+	return setSecretRefs(fileName, secretRefs)
+}
+
+// mergeStoredChanges
+// Merges the changes that were stored to the current environment since the
+// working copy was created into the working configuration files. Files that
+// can not be merged get conflict markers and the commit is refused until they
+// are resolved. Working copies without a recorded base are committed as they
+// are
+func mergeStoredChanges(config JorgeConfig, projectRoot string) *EncapsulatedError {
+	base, found, err := getWorkingCopyBase(config.CurrentEnv)
+	if err != nil || !found {
+		return err
+	}
+
+	baseDir, err := getBaseDir(config.CurrentEnv)
+	if err != nil {
+		return err
+	}
+
+	if len(base.Conflicts) > 0 {
+		unresolved := []string{}
+		for _, trackedFile := range config.TrackedFiles() {
+			_, trackedFileName := filepath.Split(trackedFile)
+			if !Contains(base.Conflicts, trackedFileName) {
+				continue
+			}
+
+			if data, readErr := ioutil.ReadFile(filepath.Join(projectRoot, trackedFile)); readErr == nil && hasConflictMarkers(data) {
+				unresolved = append(unresolved, trackedFile)
+			}
+		}
+
+		if len(unresolved) > 0 {
+			encErr := EncapsulatedError{
+				OriginalErr: fmt.Errorf("%s still hold conflict markers", strings.Join(unresolved, ", ")),
+				Message:     ErrorCode.Str(E137),
+				Solution:    SolutionMessage.Str(S129, strings.Join(unresolved, ", ")),
+				Code:        137,
+			}
+			return &encErr
+		}
+	}
+
+	meta, err := getEnvMeta(config.CurrentEnv)
+	if err != nil {
+		return err
+	}
+	theirsLabel := fmt.Sprintf("%s (revision %d)", config.CurrentEnv, meta.Revision)
+
+	conflicts := []string{}
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+
+		baseData, baseErr := ioutil.ReadFile(filepath.Join(baseDir, trackedFileName))
+		if baseErr != nil {
+			continue
+		}
+
+		storedData, err := readStoredFile(config.CurrentEnv, trackedFileName)
+		if err != nil {
+			continue
+		}
+
+		if bytes.Equal(baseData, storedData) {
+			continue
+		}
+		workingFilePath := filepath.Join(projectRoot, trackedFile)
+		if envDir, _ := getEnvDirPath(config.CurrentEnv); isLinkedTo(workingFilePath, filepath.Join(envDir, trackedFileName)) {
+			continue
+		}
+		log.Debug(fmt.Sprintf("%s of %s changed since revision %d", trackedFileName, config.CurrentEnv, base.Revision))
+
+		workingData, readErr := ioutil.ReadFile(workingFilePath)
+		if readErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: readErr,
+				Message:     ErrorCode.Str(E107),
+				Solution:    SolutionMessage.Str(S003, workingFilePath),
+				Code:        107,
+			}
+			return &encErr
+		}
+
+		mergedData, clean := mergeConfigFile(trackedFileName, baseData, unrenderConfigFile(trackedFileName, workingData), storedData, theirsLabel)
+		if err := writeWorkingFile(workingFilePath, config, config.CurrentEnv, mergedData); err != nil {
+			return err
+		}
+
+		if !clean {
+			conflicts = append(conflicts, trackedFileName)
+		}
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	// The stored files become the base, so that committing the resolved files
+	// does not merge the same changes again
+	if err := recordWorkingCopyBase(config, config.CurrentEnv, conflicts); err != nil {
+		return err
+	}
+
+	encErr := EncapsulatedError{
+		OriginalErr: fmt.Errorf("%s changed since revision %d", config.CurrentEnv, base.Revision),
+		Message:     ErrorCode.Str(E137),
+		Solution:    SolutionMessage.Str(S129, strings.Join(conflicts, ", ")),
+		Code:        137,
+	}
+	return &encErr
+}
+
+// Resolve
+// Finishes a commit that stopped on conflicts, once the conflict markers were
+// removed from the working configuration files
+func Resolve() *EncapsulatedError {
+	config, err := getInternalConfig()
+	if err != nil {
+		return err
+	}
+
+	base, found, err := getWorkingCopyBase(config.CurrentEnv)
+	if err != nil {
+		return err
+	}
+
+	if !found || len(base.Conflicts) == 0 {
+		encErr := EncapsulatedError{
+			OriginalErr: ErrorCode.Err(E138),
+			Message:     ErrorCode.Str(E138),
+			Solution:    SolutionMessage.Str(S130),
+			Code:        138,
+		}
+		return &encErr
+	}
+
+	return CommitCurrentEnv()
+}
+
+// mergeConfigFile
+// Performs a three-way merge of a configuration file. The lines are merged
+// first, and when they conflict the keys of the file are merged instead. It
+// returns false, along with the contents marked with the conflicts, when
+// neither of them succeeds
+func mergeConfigFile(fileName string, base []byte, ours []byte, theirs []byte, theirsLabel string) ([]byte, bool) {
+	switch {
+	case bytes.Equal(ours, theirs), bytes.Equal(theirs, base):
+		return ours, true
+	case bytes.Equal(ours, base):
+		return theirs, true
+	}
+
+	mergedLines, clean := mergeLines(base, ours, theirs, theirsLabel)
+	if clean {
+		return mergedLines, true
+	}
+
+	var mergedKeys []byte
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json", ".yml", ".yaml":
+		mergedKeys, clean = mergeStructuredKeys(fileName, base, ours, theirs)
+	default:
+		mergedKeys, clean = mergeDotenvKeys(base, ours, theirs)
+	}
+
+	if clean {
+		return mergedKeys, true
+	}
+
+	return mergedLines, false
+}
+
+// splitLinesWithEndings
+// Splits the contents of a file to lines, keeping their line endings
+func splitLinesWithEndings(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// matchLines
+// Matches the lines of two files along their longest common subsequence. It
+// returns, for every line of a, the index of the matching line of b or -1
+func matchLines(a []string, b []string) []int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	matches := make([]int, len(a))
+	i, j := 0, 0
+	for i < len(a) {
+		switch {
+		case j < len(b) && a[i] == b[j]:
+			matches[i] = j
+			i, j = i+1, j+1
+		case j < len(b) && lengths[i+1][j] < lengths[i][j+1]:
+			j++
+		default:
+			matches[i] = -1
+			i++
+		}
+	}
+
+	return matches
+}
+
+// mergeLines
+// Performs a three-way merge of the lines of a file. The lines that are kept
+// by both sides split the files into chunks, and a chunk conflicts when both
+// sides changed it differently. Conflicting chunks are written between
+// conflict markers
+func mergeLines(base []byte, ours []byte, theirs []byte, theirsLabel string) ([]byte, bool) {
+	baseLines := splitLinesWithEndings(base)
+	oursLines := splitLinesWithEndings(ours)
+	theirsLines := splitLinesWithEndings(theirs)
+
+	newline := "\n"
+	if bytes.Contains(ours, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+
+	var merged strings.Builder
+	clean := true
+
+	writeLines := func(lines []string, terminate bool) {
+		for i, line := range lines {
+			merged.WriteString(line)
+			if terminate && i == len(lines)-1 && !strings.HasSuffix(line, "\n") {
+				merged.WriteString(newline)
+			}
+		}
+	}
+
+	mergeChunk := func(baseChunk []string, oursChunk []string, theirsChunk []string) {
+		switch {
+		case reflect.DeepEqual(oursChunk, theirsChunk), reflect.DeepEqual(theirsChunk, baseChunk):
+			writeLines(oursChunk, false)
+		case reflect.DeepEqual(oursChunk, baseChunk):
+			writeLines(theirsChunk, false)
+		default:
+			clean = false
+			merged.WriteString(conflictOursMarker + "working copy" + newline)
+			writeLines(oursChunk, true)
+			merged.WriteString(conflictSeparator + newline)
+			writeLines(theirsChunk, true)
+			merged.WriteString(conflictTheirsMarker + theirsLabel + newline)
+		}
+	}
+
+	oursMatches := matchLines(baseLines, oursLines)
+	theirsMatches := matchLines(baseLines, theirsLines)
+
+	b, o, t := 0, 0, 0
+	for i, line := range baseLines {
+		if oursMatches[i] < 0 || theirsMatches[i] < 0 {
+			continue
+		}
+
+		mergeChunk(baseLines[b:i], oursLines[o:oursMatches[i]], theirsLines[t:theirsMatches[i]])
+		merged.WriteString(line)
+		b, o, t = i+1, oursMatches[i]+1, theirsMatches[i]+1
+	}
+	mergeChunk(baseLines[b:], oursLines[o:], theirsLines[t:])
+
+	return []byte(merged.String()), clean
+}
+
+// keyedLines
+// Indexes the KEY=VALUE lines of a file by their key and returns the other
+// lines in their order. It returns false when a key is defined twice
+func keyedLines(lines []string) (map[string]string, []string, bool) {
+	keyed := map[string]string{}
+	other := []string{}
+
+	for _, line := range lines {
+		key := dotenvKey(line)
+		if len(key) == 0 {
+			other = append(other, strings.TrimRight(line, "\r\n"))
+			continue
+		}
+
+		if _, found := keyed[key]; found {
+			return keyed, other, false
+		}
+		keyed[key] = strings.TrimRight(line, "\r\n")
+	}
+
+	return keyed, other, true
+}
+
+// mergeDotenvKeys
+// Performs a three-way merge of the keys of a KEY=VALUE file. The keys that
+// were changed only in the working copy are applied to the stored file. It
+// returns false when both sides changed the same key, or when the working copy
+// changed lines other than keys
+func mergeDotenvKeys(base []byte, ours []byte, theirs []byte) ([]byte, bool) {
+	baseLines := splitLinesWithEndings(base)
+	oursLines := splitLinesWithEndings(ours)
+	theirsLines := splitLinesWithEndings(theirs)
+
+	baseKeys, baseOther, baseOk := keyedLines(baseLines)
+	oursKeys, oursOther, oursOk := keyedLines(oursLines)
+	theirsKeys, _, theirsOk := keyedLines(theirsLines)
+	if !baseOk || !oursOk || !theirsOk || !reflect.DeepEqual(baseOther, oursOther) {
+		return ours, false
+	}
+
+	newline := "\n"
+	if bytes.Contains(theirs, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+
+	keys := []string{}
+	for _, lines := range [][]string{baseLines, oursLines} {
+		for _, line := range lines {
+			if key := dotenvKey(line); len(key) > 0 && !Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	merged := append([]string{}, theirsLines...)
+	for _, key := range keys {
+		baseLine, inBase := baseKeys[key]
+		oursLine, inOurs := oursKeys[key]
+		theirsLine, inTheirs := theirsKeys[key]
+
+		if inBase == inOurs && baseLine == oursLine {
+			continue
+		}
+		if inTheirs == inOurs && theirsLine == oursLine {
+			continue
+		}
+		if inTheirs != inBase || theirsLine != baseLine {
+			return ours, false
+		}
+
+		index := -1
+		for i, line := range merged {
+			if dotenvKey(line) == key {
+				index = i
+			}
+		}
+
+		switch {
+		case !inOurs:
+			merged = append(merged[:index], merged[index+1:]...)
+		case index >= 0:
+			merged[index] = oursLine + newline
+		default:
+			if len(merged) > 0 && !strings.HasSuffix(merged[len(merged)-1], "\n") {
+				merged[len(merged)-1] += newline
+			}
+			merged = append(merged, oursLine+newline)
+		}
+	}
+
+	return []byte(strings.Join(merged, "")), true
+}
+
+// mergeStructuredKeys
+// Performs a three-way merge of the keys of a JSON or YAML file. The merged
+// file is encoded again, so its comments and formatting are not kept
+func mergeStructuredKeys(fileName string, base []byte, ours []byte, theirs []byte) ([]byte, bool) {
+	if strings.ToLower(filepath.Ext(fileName)) == ".json" {
+		baseDoc, baseErr := decodeOrderedJSON(base)
+		oursDoc, oursErr := decodeOrderedJSON(ours)
+		theirsDoc, theirsErr := decodeOrderedJSON(theirs)
+		if baseErr != nil || oursErr != nil || theirsErr != nil {
+			return ours, false
+		}
+
+		merged, _, clean := mergeValues(baseDoc, true, oursDoc, true, theirsDoc, true)
+		if !clean {
+			return ours, false
+		}
+
+		encoded, err := encodeOrderedJSON(merged)
+		if err != nil {
+			return ours, false
+		}
+		return matchLineEndings(ours, encoded), true
+	}
+
+	var baseDoc, oursDoc, theirsDoc yaml.MapSlice
+	if yaml.Unmarshal(base, &baseDoc) != nil || yaml.Unmarshal(ours, &oursDoc) != nil || yaml.Unmarshal(theirs, &theirsDoc) != nil {
+		return ours, false
+	}
+
+	merged, _, clean := mergeValues(baseDoc, true, oursDoc, true, theirsDoc, true)
+	if !clean {
+		return ours, false
+	}
+
+	encoded, err := yaml.Marshal(merged)
+	if err != nil {
+		return ours, false
+	}
+	return matchLineEndings(ours, encoded), true
+}
+
+// mergeValues
+// Performs a three-way merge of a value of a decoded JSON or YAML document.
+// The found flags tell whether each side holds the value at all. Objects are
+// merged key by key, while any other value conflicts when both sides changed
+// it differently. It returns the merged value, whether it exists and whether
+// the merge succeeded
+func mergeValues(base interface{}, inBase bool, ours interface{}, inOurs bool, theirs interface{}, inTheirs bool) (interface{}, bool, bool) {
+	same := func(a interface{}, inA bool, b interface{}, inB bool) bool {
+		return inA == inB && reflect.DeepEqual(a, b)
+	}
+
+	switch {
+	case same(ours, inOurs, theirs, inTheirs), same(theirs, inTheirs, base, inBase):
+		return ours, inOurs, true
+	case same(ours, inOurs, base, inBase):
+		return theirs, inTheirs, true
+	case !inBase || !inOurs || !inTheirs:
+		return nil, false, false
+	}
+
+	switch baseObject := base.(type) {
+	case map[string]interface{}:
+		oursObject, oursOk := ours.(map[string]interface{})
+		theirsObject, theirsOk := theirs.(map[string]interface{})
+		if !oursOk || !theirsOk {
+			return nil, false, false
+		}
+
+		merged := map[string]interface{}{}
+		for _, object := range []map[string]interface{}{baseObject, oursObject, theirsObject} {
+			for key := range object {
+				if _, done := merged[key]; done {
+					continue
+				}
+
+				baseValue, inBase := baseObject[key]
+				oursValue, inOurs := oursObject[key]
+				theirsValue, inTheirs := theirsObject[key]
+
+				value, found, clean := mergeValues(baseValue, inBase, oursValue, inOurs, theirsValue, inTheirs)
+				if !clean {
+					return nil, false, false
+				}
+				if found {
+					merged[key] = value
+				}
+			}
+		}
+
+		return merged, true, true
+	case yaml.MapSlice:
+		oursObject, oursOk := ours.(yaml.MapSlice)
+		theirsObject, theirsOk := theirs.(yaml.MapSlice)
+		if !oursOk || !theirsOk {
+			return nil, false, false
+		}
+
+		lookup := func(object yaml.MapSlice, key string) (interface{}, bool) {
+			for _, item := range object {
+				if fmt.Sprint(item.Key) == key {
+					return item.Value, true
+				}
+			}
+			return nil, false
+		}
+
+		// The merged keys keep the order of the stored file, followed by the
+		// keys that only the working copy holds
+		merged := yaml.MapSlice{}
+		seen := map[string]bool{}
+		for _, object := range []yaml.MapSlice{theirsObject, oursObject, baseObject} {
+			for _, item := range object {
+				key := fmt.Sprint(item.Key)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				baseValue, inBase := lookup(baseObject, key)
+				oursValue, inOurs := lookup(oursObject, key)
+				theirsValue, inTheirs := lookup(theirsObject, key)
+
+				value, found, clean := mergeValues(baseValue, inBase, oursValue, inOurs, theirsValue, inTheirs)
+				if !clean {
+					return nil, false, false
+				}
+				if found {
+					merged = append(merged, yaml.MapItem{Key: item.Key, Value: value})
+				}
+			}
+		}
+
+		return merged, true, true
+	}
+
+	return nil, false, false
+}
@@ -0,0 +1,88 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeConfigFile(t *testing.T) {
+	cases := []struct {
+		name     string
+		fileName string
+		base     string
+		ours     string
+		theirs   string
+		merged   string
+		clean    bool
+	}{
+		{"lines", "app.toml", "a = 1\nb = 2\nc = 3\n", "a = 10\nb = 2\nc = 3\n", "a = 1\nb = 2\nc = 30\n", "a = 10\nb = 2\nc = 30\n", true},
+		{"adjacent keys", ".env", "A=1\nB=2\n", "A=1\nB=20\n", "A=1\nB=2\nC=3\n", "A=1\nB=20\nC=3\n", true},
+		{"yaml keys", "app.yml", "db:\n  host: localhost\n  port: 5432\n", "db:\n  host: db\n  port: 5432\n", "db:\n  host: localhost\n  port: 5433\n", "db:\n  host: db\n  port: 5433\n", true},
+		{"json keys", "app.json", "{\n  \"id\": 12345678901234567890,\n  \"z\": 1,\n  \"a\": 2\n}\n", "{\n  \"id\": 12345678901234567890,\n  \"z\": 10,\n  \"a\": 2\n}\n", "{\n  \"id\": 12345678901234567890,\n  \"z\": 1,\n  \"a\": 3\n}\n", "{\n  \"id\": 12345678901234567890,\n  \"z\": 10,\n  \"a\": 3\n}\n", true},
+		{"conflict", ".env", "A=1\nB=2\n", "A=1\nB=20\n", "A=1\nB=200\n", "A=1\n<<<<<<< working copy\nB=20\n=======\nB=200\n>>>>>>> default (revision 2)\n", false},
+	}
+
+	for _, c := range cases {
+		merged, clean := mergeConfigFile(c.fileName, []byte(c.base), []byte(c.ours), []byte(c.theirs), "default (revision 2)")
+		if clean != c.clean || string(merged) != c.merged {
+			t.Errorf("%s: expected %q (%v), but found %q (%v)", c.name, c.merged, c.clean, merged, clean)
+		}
+	}
+}
+
+func TestCommitMergesStoredChanges(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "default"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.Remove(filepath.Join(testingRoot, ".env"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env"), []byte("HOST=localhost\nPORT=80\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", envMetaFileName), []byte("revision: 1\n"), 0600)
+
+	if err := RestoreEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another terminal stores a change, while the working copy changes too
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env"), []byte("HOST=localhost\nPORT=8080\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".env"), []byte("HOST=staging\nPORT=80\n"), 0600)
+
+	if err := CommitCurrentEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env")); string(data) != "HOST=staging\nPORT=8080\n" {
+		t.Fatalf("Changes were not merged: %s", data)
+	}
+
+	if err := Resolve(); err == nil || err.Code != 138 {
+		t.Fatalf("Expected code %d, but found %v", 138, err)
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env"), []byte("HOST=localhost\nPORT=8080\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".env"), []byte("HOST=production\nPORT=8080\n"), 0600)
+
+	if err := CommitCurrentEnv(); err == nil || err.Code != 137 {
+		t.Fatalf("Expected code %d, but found %v", 137, err)
+	}
+
+	if err := Resolve(); err == nil || err.Code != 137 {
+		t.Fatalf("Expected code %d, but found %v", 137, err)
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, ".env"), []byte("HOST=production\nPORT=8080\n"), 0600)
+
+	if err := Resolve(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env")); string(data) != "HOST=production\nPORT=8080\n" {
+		t.Fatalf("Resolved file was not committed: %s", data)
+	}
+}
@@ -0,0 +1,290 @@
+package jorge
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const envMetaFileName = ".meta.yml"
+const expiryDateLayout = "2006-01-02"
+
+type EnvMeta struct {
+	Description string                    `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string                  `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Creator     string                    `json:"creator,omitempty" yaml:"creator,omitempty"`
+	Parent      string                    `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Include     []string                  `json:"include,omitempty" yaml:"include,omitempty"`
+	Revision    int                       `json:"revision" yaml:"revision,omitempty"`
+	Created     time.Time                 `json:"created" yaml:"created,omitempty"`
+	Updated     time.Time                 `json:"updated" yaml:"updated,omitempty"`
+	Expires     time.Time                 `json:"expires" yaml:"expires,omitempty"`
+	Files       map[string]FileAttributes `json:"files,omitempty" yaml:"files,omitempty"`
+}
+
+// IsExpired
+// Determines whether the expiry date of the environment has passed
+func (m EnvMeta) IsExpired() bool {
+	return !m.Expires.IsZero() && time.Now().After(m.Expires)
+}
+
+// HasTag
+// Determines whether the environment is labeled with the tag
+func (m EnvMeta) HasTag(tag string) bool {
+	return Contains(m.Tags, tag)
+}
+
+// validateEnvName
+// Environments are directories of the envs directory, so their names can not
+// contain path separators or point to another directory
+func validateEnvName(name string) *EncapsulatedError {
+	if len(name) == 0 || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("invalid environment name %q", name),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S145, name),
+			Code:        118,
+		}
+		return &encErr
+	}
+
+	return nil
+}
+
+// getEnvDirPath
+// Returns the absolute path to the directory of an existing environment
+func getEnvDirPath(envName string) (string, *EncapsulatedError) {
+	if err := validateEnvName(envName); err != nil {
+		return "", err
+	}
+
+	envsDir, err := getEnvsDirPath()
+	if err != nil {
+		return "", err
+	}
+
+	envDir := filepath.Join(envsDir, envName)
+
+	if info, statErr := os.Stat(envDir); statErr != nil || !info.IsDir() {
+		encErr := EncapsulatedError{
+			OriginalErr: ErrorCode.Err(E111),
+			Message:     ErrorCode.Str(E111),
+			Solution:    SolutionMessage.Str(S105, envName),
+			Code:        111,
+		}
+		return "", &encErr
+	}
+
+	return envDir, nil
+}
+
+// getEnvMeta
+// Returns the metadata of an environment. Environments that were created
+// without metadata return an empty EnvMeta
+func getEnvMeta(envName string) (EnvMeta, *EncapsulatedError) {
+	envDir, err := getEnvDirPath(envName)
+	if err != nil {
+		return EnvMeta{}, err
+	}
+
+	metaFilePath := filepath.Join(envDir, envMetaFileName)
+	data, readErr := ioutil.ReadFile(metaFilePath)
+
+	if readErr != nil {
+		if errors.Is(readErr, os.ErrNotExist) {
+			log.Debug(fmt.Sprintf("Env %s has no metadata", envName))
+			return EnvMeta{}, nil
+		}
+
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E115),
+			Solution:    SolutionMessage.Str(S003, metaFilePath),
+			Code:        115,
+		}
+		return EnvMeta{}, &encErr
+	}
+
+	var meta EnvMeta
+	if ymlErr := yaml.Unmarshal(data, &meta); ymlErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: ymlErr,
+			Message:     ErrorCode.Str(E115),
+			Solution:    SolutionMessage.Str(S003, metaFilePath),
+			Code:        115,
+		}
+		return EnvMeta{}, &encErr
+	}
+
+	return meta, nil
+}
+
+// setEnvMeta
+// Replaces the metadata of an environment
+func setEnvMeta(envName string, meta EnvMeta) *EncapsulatedError {
+	envDir, err := getEnvDirPath(envName)
+	if err != nil {
+		return err
+	}
+
+	metaFilePath := filepath.Join(envDir, envMetaFileName)
+	data, ymlErr := yaml.Marshal(&meta)
+
+	if ymlErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: ymlErr,
+			Message:     ErrorCode.Str(E116),
+			Solution:    SolutionMessage.Str(S103, GetUser()),
+			Code:        116,
+		}
+		return &encErr
+	}
+
+	if writeErr := ioutil.WriteFile(metaFilePath, data, 0600); writeErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: writeErr,
+			Message:     ErrorCode.Str(E116),
+			Solution:    SolutionMessage.Str(S103, GetUser()),
+			Code:        116,
+		}
+		return &encErr
+	}
+
+	log.Debug(fmt.Sprintf("Wrote metadata of env %s", envName))
+	return nil
+}
+
+// touchEnvMeta
+// Records that the environment was stored. A fresh environment gets its
+// creator and creation time, while an existing one only updates its
+// modification time. Every store increases the revision of the environment.
+// Environments without metadata are left untouched
+func touchEnvMeta(envName string, created bool) *EncapsulatedError {
+	envDir, err := getEnvDirPath(envName)
+	if err != nil {
+		return err
+	}
+
+	if _, statErr := os.Stat(filepath.Join(envDir, envMetaFileName)); statErr != nil && !created {
+		return nil
+	}
+
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if created {
+		meta.Creator = GetUser()
+		meta.Created = now
+	}
+	meta.Updated = now
+	meta.Revision++
+
+	return setEnvMeta(envName, meta)
+}
+
+// setEnvParent
+// Records the environment that a new environment was created from. The new
+// environment keeps the includes of its parent, since it was stored from a
+// file that was rendered with them
+func setEnvParent(envName string, parent string) *EncapsulatedError {
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	if parentMeta, err := getEnvMeta(parent); err == nil {
+		meta.Include = parentMeta.Include
+	}
+
+	meta.Parent = parent
+	return setEnvMeta(envName, meta)
+}
+
+// GetEnvMeta
+// Returns the metadata of an environment
+func GetEnvMeta(envName string) (EnvMeta, *EncapsulatedError) {
+	return getEnvMeta(envName)
+}
+
+// DescribeEnv
+// Sets the description of an environment
+func DescribeEnv(envName string, description string) *EncapsulatedError {
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	meta.Description = description
+	return setEnvMeta(envName, meta)
+}
+
+// SetEnvExpiry
+// Sets the date (YYYY-MM-DD) after which the environment is considered expired.
+// An empty date or "never" removes the expiry date
+func SetEnvExpiry(envName string, date string) *EncapsulatedError {
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	if len(date) == 0 || date == "never" {
+		meta.Expires = time.Time{}
+	} else if expires, parseErr := time.Parse(expiryDateLayout, date); parseErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: parseErr,
+			Message:     ErrorCode.Str(E117),
+			Solution:    SolutionMessage.Str(S108, date),
+			Code:        117,
+		}
+		return &encErr
+	} else {
+		meta.Expires = expires
+	}
+
+	return setEnvMeta(envName, meta)
+}
+
+// AddEnvTag
+// Labels an environment with a tag. Adding an existing tag does nothing
+func AddEnvTag(envName string, tag string) *EncapsulatedError {
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	if meta.HasTag(tag) {
+		return nil
+	}
+
+	meta.Tags = append(meta.Tags, tag)
+	return setEnvMeta(envName, meta)
+}
+
+// RemoveEnvTag
+// Removes a tag from an environment
+func RemoveEnvTag(envName string, tag string) *EncapsulatedError {
+	meta, err := getEnvMeta(envName)
+	if err != nil {
+		return err
+	}
+
+	tags := []string{}
+	for _, existingTag := range meta.Tags {
+		if existingTag != tag {
+			tags = append(tags, existingTag)
+		}
+	}
+
+	meta.Tags = tags
+	return setEnvMeta(envName, meta)
+}
@@ -0,0 +1,111 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreConfigFileCreatesMetadata(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	os.WriteFile(filepath.Join(testingRoot, "mainTestConfig"), []byte("mock config contents"), 0600)
+	defer os.Remove(filepath.Join(testingRoot, "mainTestConfig"))
+
+	if _, err := StoreConfigFile(filepath.Join(testingRoot, "mainTestConfig"), "newMockEnv"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if meta, err := getEnvMeta("newMockEnv"); err != nil {
+		t.Fatal(err)
+	} else if meta.Creator != GetUser() {
+		t.Fatalf("Expected creator %s, but found %s", GetUser(), meta.Creator)
+	} else if meta.Created.IsZero() || meta.Updated.IsZero() {
+		t.Fatal("Timestamps were not recorded")
+	}
+}
+
+func TestEnvTags(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	if err := AddEnvTag("mockEnv", "backend"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AddEnvTag("mockEnv", "backend"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AddEnvTag("mockEnv", "tmp"); err != nil {
+		t.Fatal(err)
+	}
+
+	if meta, err := getEnvMeta("mockEnv"); err != nil {
+		t.Fatal(err)
+	} else if len(meta.Tags) != 2 {
+		t.Fatalf("Expected 2 tags, but found %v", meta.Tags)
+	}
+
+	if err := RemoveEnvTag("mockEnv", "backend"); err != nil {
+		t.Fatal(err)
+	}
+
+	if meta, err := getEnvMeta("mockEnv"); err != nil {
+		t.Fatal(err)
+	} else if meta.HasTag("backend") || !meta.HasTag("tmp") {
+		t.Fatalf("Unexpected tags %v", meta.Tags)
+	}
+
+	if err := AddEnvTag("missingEnv", "tmp"); err == nil {
+		t.Fatal("Tagged an environment that does not exist")
+	}
+}
+
+func TestSetEnvExpiry(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	if err := SetEnvExpiry("mockEnv", "not a date"); err == nil {
+		t.Fatal("Accepted an invalid date")
+	}
+
+	yesterday := time.Now().AddDate(0, 0, -1).Format(expiryDateLayout)
+	if err := SetEnvExpiry("mockEnv", yesterday); err != nil {
+		t.Fatal(err)
+	}
+
+	if meta, err := getEnvMeta("mockEnv"); err != nil {
+		t.Fatal(err)
+	} else if !meta.IsExpired() {
+		t.Fatal("Environment is not marked as expired")
+	}
+
+	if err := SetEnvExpiry("mockEnv", "never"); err != nil {
+		t.Fatal(err)
+	}
+
+	if meta, err := getEnvMeta("mockEnv"); err != nil {
+		t.Fatal(err)
+	} else if meta.IsExpired() || !meta.Expires.IsZero() {
+		t.Fatal("Expiry date was not removed")
+	}
+}
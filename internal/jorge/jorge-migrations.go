@@ -0,0 +1,236 @@
+package jorge
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// storeVersion is the version of the .jorge layout that this binary writes.
+// Stores without a version field were created before versioning and are
+// treated as version 0
+const storeVersion = 1
+const backupsDirName = "backups"
+
+type storeMigration struct {
+	from        int
+	description string
+	migrate     func(jorgeDir string) error
+}
+
+// storeMigrations holds the steps that upgrade a store to the next version.
+// Every step upgrades the store from the version `from` to `from + 1`
+var storeMigrations = []storeMigration{
+	{
+		from:        0,
+		description: "Record the metadata of the existing environments",
+		migrate:     migrateV0ToV1,
+	},
+}
+
+// migrateStore
+// Upgrades the store found at jorgeDir from the given version to the current
+// one. The store is snapshotted and a copy of it is kept under .jorge/backups
+// before any change
+func migrateStore(jorgeDir string, version int) *EncapsulatedError {
+	if version > storeVersion {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("store version %d is newer than the supported version %d", version, storeVersion),
+			Message:     ErrorCode.Str(E119),
+			Solution:    SolutionMessage.Str(S110, version),
+			Code:        119,
+		}
+		return &encErr
+	}
+
+	if version == storeVersion {
+		return nil
+	}
+
+	// The settings of the store can not be read before it is upgraded, so the
+	// snapshot follows the default retention policy
+	if err := takeAutomaticSnapshot(jorgeDir, JorgeSnapshots{}, fmt.Sprintf("migrate-v%d", version)); err != nil {
+		return err
+	}
+
+	backupDir := filepath.Join(jorgeDir, backupsDirName, fmt.Sprintf("v%d-%s", version, time.Now().UTC().Format("20060102T150405")))
+	if err := copyDir(jorgeDir, backupDir, []string{filepath.Join(jorgeDir, backupsDirName), filepath.Join(jorgeDir, snapshotsDirName), filepath.Join(jorgeDir, journalDirName)}); err != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: err,
+			Message:     ErrorCode.Str(E120),
+			Solution:    SolutionMessage.Str(S103, GetUser()),
+			Code:        120,
+		}
+		return &encErr
+	}
+	log.Debug(fmt.Sprintf("Backed up the jorge directory to %s", backupDir))
+
+	for _, migration := range storeMigrations {
+		if migration.from < version {
+			continue
+		}
+
+		log.Debug(fmt.Sprintf("Migrating store from version %d: %s", migration.from, migration.description))
+
+		migrateErr := migration.migrate(jorgeDir)
+		if migrateErr == nil {
+			migrateErr = setStoreVersion(jorgeDir, migration.from+1)
+		}
+
+		if migrateErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: migrateErr,
+				Message:     ErrorCode.Str(E120),
+				Solution:    SolutionMessage.Str(S111, backupDir),
+				Code:        120,
+			}
+			return &encErr
+		}
+	}
+
+	log.Info(fmt.Sprintf("Upgraded the jorge directory to version %d. A backup was kept at %s", storeVersion, backupDir))
+	return nil
+}
+
+// setStoreVersion
+// Updates the version field of config.yml without decoding the rest of it, so
+// that it works for layouts that are older than the current JorgeConfig
+func setStoreVersion(jorgeDir string, version int) error {
+	configFilePath := filepath.Join(jorgeDir, configFileName)
+
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return err
+	}
+
+	var config yaml.MapSlice
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return err
+	}
+
+	found := false
+	for i := range config {
+		if config[i].Key == "version" {
+			config[i].Value = version
+			found = true
+		}
+	}
+
+	if !found {
+		config = append(config, yaml.MapItem{Key: "version", Value: version})
+	}
+
+	if data, err = yaml.Marshal(config); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(configFilePath, data, privateFileMode)
+}
+
+// migrateV0ToV1
+// Stores created before versioning have no environment metadata. The metadata
+// is created from the modification time of the stored files
+func migrateV0ToV1(jorgeDir string) error {
+	envsDir := filepath.Join(jorgeDir, "envs")
+
+	entries, err := ioutil.ReadDir(envsDir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		metaFilePath := filepath.Join(envsDir, entry.Name(), envMetaFileName)
+		if _, err := os.Stat(metaFilePath); err == nil {
+			continue
+		}
+
+		meta := EnvMeta{}
+		if files, err := ioutil.ReadDir(filepath.Join(envsDir, entry.Name())); err == nil {
+			for _, file := range files {
+				if file.Mode().IsRegular() && file.ModTime().After(meta.Updated) {
+					meta.Updated = file.ModTime().UTC().Truncate(time.Second)
+				}
+			}
+		}
+		meta.Created = meta.Updated
+
+		data, err := yaml.Marshal(&meta)
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(metaFilePath, data, privateFileMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyDir
+// Copies the contents of a directory recursively, skipping the excluded paths
+func copyDir(source string, destination string, excluded []string) error {
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if Contains(excluded, path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, relativePath)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, privateDirMode)
+		}
+
+		if !info.Mode().IsRegular() {
+			log.Debug(fmt.Sprintf("Skipping %s while copying, it is not a regular file", path))
+			return nil
+		}
+
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+// copyFile
+// Copies a regular file, creating the destination with the given mode
+func copyFile(source string, destination string, mode os.FileMode) error {
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destinationFile, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(destinationFile, sourceFile); err != nil {
+		destinationFile.Close()
+		return err
+	}
+
+	return destinationFile.Close()
+}
@@ -0,0 +1,66 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetInternalConfigMigratesLegacyStore(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "mainTestConfig"), []byte("mock config contents"), 0600)
+
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("currentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
+		t.FailNow()
+	}
+
+	if config, err := getInternalConfig(); err != nil {
+		t.Fatal(err)
+	} else if config.Version != storeVersion || config.CurrentEnv != "mockEnv" {
+		t.Fatalf("Unexpected config after the migration %v", config)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(testingRoot, ".jorge", "config.yml")); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(data), "version: 1") {
+		t.Fatal("Store version was not recorded")
+	}
+
+	if _, err := os.Stat(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", envMetaFileName)); err != nil {
+		t.Fatal("Environment metadata was not created")
+	}
+
+	if backups, err := os.ReadDir(filepath.Join(testingRoot, ".jorge", backupsDirName)); err != nil || len(backups) != 1 {
+		t.Fatal("Backup of the store was not created")
+	} else if _, err := os.Stat(filepath.Join(testingRoot, ".jorge", backupsDirName, backups[0].Name(), "envs", "mockEnv", "mainTestConfig")); err != nil {
+		t.Fatal("Backup does not contain the environments")
+	}
+}
+
+func TestGetInternalConfigRefusesNewerStore(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 999\ncurrentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
+		t.FailNow()
+	}
+
+	if _, err := getInternalConfig(); err == nil {
+		t.Fatal("Opened a store that is newer than the supported version")
+	} else if err.Code != 119 {
+		t.Fatalf("Expected code %d, but found %d", 119, err.Code)
+	}
+}
@@ -0,0 +1,241 @@
+package jorge
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// ProfileSwitch
+// An environment that a profile uses in a project
+type ProfileSwitch struct {
+	Project string `json:"project" yaml:"project"`
+	Env     string `json:"env" yaml:"env"`
+}
+
+// Profile
+// A named group of environments that are used together
+type Profile struct {
+	Name     string          `json:"name" yaml:"name"`
+	Switches []ProfileSwitch `json:"switches" yaml:"switches"`
+}
+
+// profileError
+// Reports a profile that can not be used
+func profileError(err error, profile string) *EncapsulatedError {
+	encErr := EncapsulatedError{
+		OriginalErr: err,
+		Message:     ErrorCode.Str(E150),
+		Solution:    SolutionMessage.Str(S143, profile, err),
+		Code:        150,
+	}
+	return &encErr
+}
+
+// readMonorepoConfig
+// Returns the config.yml of the store at the root of the monorepo, which
+// holds the profiles
+func readMonorepoConfig(store string) (JorgeConfig, *EncapsulatedError) {
+	configFilePath := filepath.Join(store, configFileName)
+	data, readErr := ioutil.ReadFile(configFilePath)
+
+	var config JorgeConfig
+	if readErr == nil {
+		readErr = yaml.Unmarshal(data, &config)
+	}
+
+	if readErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E105),
+			Solution:    SolutionMessage.Str(S102),
+			Code:        105,
+		}
+		return JorgeConfig{}, &encErr
+	}
+
+	return config, nil
+}
+
+// selectMonorepoProject
+// Makes the following operations act on a project of the monorepo
+func selectMonorepoProject(root string, project string) {
+	if project == RootProject {
+		SetProjectDir(root)
+	} else {
+		SetProjectDir(project)
+	}
+}
+
+// resolveProfile
+// Resolves the entries of a profile to the projects they switch. An entry is
+// the name of a project, . for the project at the root, or the path of a
+// tracked file relative to the root of the monorepo. The files of a project
+// share its current environment, so they can not be mapped to different ones
+func resolveProfile(root string, store string, name string, entries map[string]string) ([]ProfileSwitch, *EncapsulatedError) {
+	projects := append([]string{RootProject}, readProjectNames(store)...)
+	trackedBy := map[string]string{}
+	for _, project := range projects {
+		projectStore, projectRoot := store, root
+		if project != RootProject {
+			location := namedProjectLocation(root, store, "", project)
+			projectStore, projectRoot = location.Store, location.Root
+		}
+
+		if config, err := readMonorepoConfig(projectStore); err == nil && len(config.ConfigFilePath) > 0 {
+			for _, trackedFile := range config.TrackedFiles() {
+				trackedBy[filepath.Join(projectRoot, trackedFile)] = project
+			}
+		}
+	}
+
+	keys := []string{}
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	envByProject := map[string]string{}
+	switches := []ProfileSwitch{}
+	for _, key := range keys {
+		project := filepath.ToSlash(filepath.Clean(key))
+		if !Contains(projects, project) {
+			trackingProject, found := trackedBy[filepath.Join(root, filepath.FromSlash(project))]
+			if !found {
+				return []ProfileSwitch{}, profileError(fmt.Errorf("%s is neither a project nor a tracked file", key), name)
+			}
+			project = trackingProject
+		}
+
+		if env, found := envByProject[project]; found {
+			if env != entries[key] {
+				return []ProfileSwitch{}, profileError(fmt.Errorf("the files of %s are mapped to %s and %s", project, env, entries[key]), name)
+			}
+			continue
+		}
+
+		envByProject[project] = entries[key]
+		switches = append(switches, ProfileSwitch{Project: project, Env: entries[key]})
+	}
+
+	return switches, nil
+}
+
+// ListProfiles
+// Returns the profiles of the monorepo, sorted by name
+func ListProfiles() ([]Profile, *EncapsulatedError) {
+	root, store, err := getMonorepo()
+	if err != nil {
+		return []Profile{}, err
+	}
+
+	config, err := readMonorepoConfig(store)
+	if err != nil {
+		return []Profile{}, err
+	}
+
+	names := []string{}
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	profiles := []Profile{}
+	for _, name := range names {
+		switches, err := resolveProfile(root, store, name, config.Profiles[name])
+		if err != nil {
+			return profiles, err
+		}
+		profiles = append(profiles, Profile{Name: name, Switches: switches})
+	}
+
+	return profiles, nil
+}
+
+// ProfileUse
+// Uses the environments of a profile in every project it maps. Every
+// environment is checked before any project is switched, and the projects
+// that were switched are restored when one of them fails
+func ProfileUse(name string) ([]ProfileSwitch, *EncapsulatedError) {
+	root, store, err := getMonorepo()
+	if err != nil {
+		return []ProfileSwitch{}, err
+	}
+
+	config, err := readMonorepoConfig(store)
+	if err != nil {
+		return []ProfileSwitch{}, err
+	}
+
+	entries, found := config.Profiles[name]
+	if !found {
+		names := []string{}
+		for profile := range config.Profiles {
+			names = append(names, profile)
+		}
+		sort.Strings(names)
+
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("profile %s is not defined", name),
+			Message:     ErrorCode.Str(E149),
+			Solution:    SolutionMessage.Str(S142, strings.Join(names, ", ")),
+			Code:        149,
+		}
+		return []ProfileSwitch{}, &encErr
+	}
+
+	switches, err := resolveProfile(root, store, name, entries)
+	if err != nil {
+		return []ProfileSwitch{}, err
+	}
+
+	previousProject := projectDirOverride
+	defer SetProjectDir(previousProject)
+
+	for _, profileSwitch := range switches {
+		selectMonorepoProject(root, profileSwitch.Project)
+		if _, err := getEnvDirPath(profileSwitch.Env); err != nil {
+			err.Solution = fmt.Sprintf("%s (project %s)", err.Solution, profileSwitch.Project)
+			return []ProfileSwitch{}, err
+		}
+	}
+
+	// The projects that were switched are switched back when one of them fails
+	tx, err := beginTransaction(store, "profile")
+	if err != nil {
+		return []ProfileSwitch{}, err
+	}
+	defer tx.close()
+
+	for i, profileSwitch := range switches {
+		selectMonorepoProject(root, profileSwitch.Project)
+
+		projectConfig, err := getInternalConfig()
+		if err != nil {
+			return switches[:i], err
+		}
+
+		location, err := locateProject()
+		if err != nil {
+			return switches[:i], err
+		}
+
+		if err := tx.trackEnvSwitch(projectConfig, location, projectConfig.CurrentEnv, profileSwitch.Env); err != nil {
+			return switches[:i], err
+		}
+
+		if _, err := UseConfigFile(profileSwitch.Env, false); err != nil {
+			log.Warn(fmt.Sprintf("Could not use %s in %s, restoring the projects that were switched", profileSwitch.Env, profileSwitch.Project))
+			err.Solution = fmt.Sprintf("%s (project %s)", err.Solution, profileSwitch.Project)
+			return []ProfileSwitch{}, err
+		}
+	}
+
+	tx.commit()
+	return switches, nil
+}
@@ -0,0 +1,87 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileUse(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, "services", "api"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, "services", "web"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, "services"))
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.Remove(filepath.Join(testingRoot, ".gitignore"))
+
+	apiFilePath := filepath.Join(testingRoot, "services", "api", ".env")
+	webFilePath := filepath.Join(testingRoot, "services", "web", ".env")
+	os.WriteFile(apiFilePath, []byte("HOST=api\n"), 0600)
+	os.WriteFile(webFilePath, []byte("HOST=web\n"), 0600)
+
+	for _, project := range []string{"services/api", "services/web"} {
+		if _, err := InitNamedProject(project, []string{filepath.Join(project, ".env")}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer SetProjectDir("")
+	SetProjectDir("services/api")
+	os.WriteFile(apiFilePath, []byte("HOST=staging-api\n"), 0600)
+	if _, err := UseConfigFile("staging", true); err != nil {
+		t.Fatal(err)
+	}
+
+	SetProjectDir("services/web")
+	os.WriteFile(webFilePath, []byte("HOST=cdn\n"), 0600)
+	if _, err := UseConfigFile("staging-cdn", true); err != nil {
+		t.Fatal(err)
+	}
+
+	SetProjectDir("")
+	if _, err := UseAll("default"); err != nil {
+		t.Fatal(err)
+	}
+
+	rootConfigPath := filepath.Join(testingRoot, ".jorge", configFileName)
+	rootConfig, _ := os.ReadFile(rootConfigPath)
+	os.WriteFile(rootConfigPath, append(rootConfig, []byte("profiles:\n  staging:\n    services/api: staging\n    services/web/.env: staging-cdn\n  mixed:\n    services/web: staging-cdn\n    services/web/.env: default\n")...), 0600)
+
+	if _, err := ProfileUse("mixed"); err == nil || err.Code != 150 {
+		t.Fatalf("Expected code %d, but found %v", 150, err)
+	}
+
+	if switches, err := ProfileUse("staging"); err != nil || len(switches) != 2 {
+		t.Fatalf("Unexpected switches %v (%v)", switches, err)
+	}
+
+	if data, _ := os.ReadFile(webFilePath); string(data) != "HOST=cdn\n" {
+		t.Fatalf("Unexpected working file %s", data)
+	}
+
+	if _, err := UseAll("default"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failing hook of the second project restores the first one
+	webConfigPath := filepath.Join(testingRoot, ".jorge", projectsDirName, "services", "web", configFileName)
+	webConfig, _ := os.ReadFile(webConfigPath)
+	os.WriteFile(webConfigPath, append(webConfig, []byte("hooks:\n  preUse:\n  - exit 1\n")...), 0600)
+
+	if _, err := ProfileUse("staging"); err == nil {
+		t.Fatal("Expected the profile to fail")
+	}
+
+	if data, _ := os.ReadFile(apiFilePath); string(data) != "HOST=api\n" {
+		t.Fatalf("Working file was not restored: %s", data)
+	}
+
+	SetProjectDir("services/api")
+	if config, err := getInternalConfig(); err != nil || config.CurrentEnv != "default" {
+		t.Fatalf("Current environment was not restored %v (%v)", config, err)
+	}
+}
@@ -0,0 +1,206 @@
+package jorge
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// RootProject is the name that stands for the project at the root of a
+// monorepo
+const RootProject = "."
+
+// getMonorepo
+// Returns the root and the store of the monorepo that contains the current
+// directory, or of the current directory when it belongs to no project
+func getMonorepo() (string, string, *EncapsulatedError) {
+	currentAbsPath, absErr := filepath.Abs(".")
+	if absErr != nil {
+		return "", "", locationError(absErr)
+	}
+
+	root, found, err := findProjectRoot(currentAbsPath)
+	if err != nil {
+		return "", "", err
+	} else if !found {
+		root = currentAbsPath
+	}
+
+	store, _, err := getStoreLocation(root)
+	return root, store, err
+}
+
+// setProjectRegistered
+// Adds or removes a named project to the config.yml of the store at the root
+// of the monorepo. The store is created when it does not exist
+func setProjectRegistered(store string, project string, registered bool) *EncapsulatedError {
+	configFilePath := filepath.Join(store, configFileName)
+	config := JorgeConfig{Version: storeVersion}
+
+	writeErr := os.MkdirAll(store, 0700)
+	if writeErr == nil {
+		if data, readErr := ioutil.ReadFile(configFilePath); readErr == nil {
+			writeErr = yaml.Unmarshal(data, &config)
+		} else if !errors.Is(readErr, os.ErrNotExist) {
+			writeErr = readErr
+		}
+	}
+
+	if writeErr == nil {
+		projects := []string{}
+		for _, existing := range config.Projects {
+			if existing != project {
+				projects = append(projects, existing)
+			}
+		}
+		if registered {
+			projects = append(projects, project)
+		}
+		config.Projects = projects
+
+		var data []byte
+		if data, writeErr = yaml.Marshal(&config); writeErr == nil {
+			writeErr = ioutil.WriteFile(configFilePath, data, 0600)
+		}
+	}
+
+	if writeErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: writeErr,
+			Message:     ErrorCode.Str(E106),
+			Solution:    SolutionMessage.Str(S103, GetUser()),
+			Code:        106,
+		}
+		return &encErr
+	}
+
+	return nil
+}
+
+// InitNamedProject
+// Initializes a project of the monorepo that contains the current directory.
+// The name of the project is the path of its directory relative to the root
+// of the monorepo, and its environments are kept under the projects directory
+// of the store at the root. The monorepo is created at the current directory
+// when it belongs to no project
+func InitNamedProject(name string, configFiles []string, interactive bool) ([]string, *EncapsulatedError) {
+	root, store, err := getMonorepo()
+	if err != nil {
+		return []string{}, err
+	}
+
+	project := filepath.ToSlash(filepath.Clean(name))
+	if filepath.IsAbs(name) || project == RootProject || project == ".." || strings.HasPrefix(project, "../") {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("%s is not a path inside of %s", name, root),
+			Message:     ErrorCode.Str(E148),
+			Solution:    SolutionMessage.Str(S141, name, root),
+			Code:        148,
+		}
+		return []string{}, &encErr
+	}
+
+	if Contains(readProjectNames(store), project) {
+		encErr := EncapsulatedError{
+			OriginalErr: ErrorCode.Err(E147),
+			Message:     ErrorCode.Str(E147),
+			Solution:    SolutionMessage.Str(S140, project, root, project),
+			Code:        147,
+		}
+		return []string{}, &encErr
+	}
+
+	if info, statErr := os.Stat(filepath.Join(root, filepath.FromSlash(project))); statErr != nil || !info.IsDir() {
+		encErr := EncapsulatedError{
+			OriginalErr: statErr,
+			Message:     ErrorCode.Str(E148),
+			Solution:    SolutionMessage.Str(S141, name, root),
+			Code:        148,
+		}
+		return []string{}, &encErr
+	}
+
+	_, storeErr := os.Stat(store)
+	createdStore := errors.Is(storeErr, os.ErrNotExist)
+
+	if err := setProjectRegistered(store, project, true); err != nil {
+		return []string{}, err
+	}
+
+	if ignorePath, inProject := getStoreIgnorePath(root, store); inProject && createdStore {
+		gitignorePath := filepath.Join(root, ".gitignore")
+		if ignored, _ := ExistsInFile(gitignorePath, ignorePath); !ignored {
+			AppendToFile(gitignorePath, ignorePath)
+		}
+	}
+
+	previousProject := projectDirOverride
+	SetProjectDir(project)
+	defer SetProjectDir(previousProject)
+
+	importedEnvs, err := Init(configFiles, interactive)
+	if err != nil {
+		if unregisterErr := setProjectRegistered(store, project, false); unregisterErr != nil {
+			log.Warn(fmt.Sprintf("%s: %s", unregisterErr.Message, unregisterErr.OriginalErr))
+		}
+		return []string{}, err
+	}
+
+	return importedEnvs, nil
+}
+
+// UseAll
+// Uses the environment in every project of the monorepo that has it, the
+// project at the root included. It returns the projects that were switched
+func UseAll(envName string) ([]string, *EncapsulatedError) {
+	root, store, err := getMonorepo()
+	if err != nil {
+		return []string{}, err
+	}
+
+	previousProject := projectDirOverride
+	defer SetProjectDir(previousProject)
+
+	projects := append([]string{RootProject}, readProjectNames(store)...)
+	switched := []string{}
+	for _, project := range projects {
+		if project == RootProject {
+			SetProjectDir(root)
+		} else {
+			SetProjectDir(project)
+		}
+
+		if config, err := getInternalConfig(); err != nil || len(config.ConfigFilePath) == 0 {
+			continue
+		}
+
+		if _, err := getEnvDirPath(envName); err != nil {
+			log.Debug(fmt.Sprintf("Project %s has no environment %s", project, envName))
+			continue
+		}
+
+		if _, err := UseConfigFile(envName, false); err != nil {
+			err.Solution = fmt.Sprintf("%s (project %s)", err.Solution, project)
+			return switched, err
+		}
+		switched = append(switched, project)
+	}
+
+	if len(switched) == 0 {
+		encErr := EncapsulatedError{
+			OriginalErr: ErrorCode.Err(E111),
+			Message:     ErrorCode.Str(E111),
+			Solution:    SolutionMessage.Str(S105, envName),
+			Code:        111,
+		}
+		return switched, &encErr
+	}
+
+	return switched, nil
+}
@@ -0,0 +1,62 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNamedProjects(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, "services", "api"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, "services", "web"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, "services"))
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.Remove(filepath.Join(testingRoot, ".gitignore"))
+
+	os.WriteFile(filepath.Join(testingRoot, "services", "api", ".env"), []byte("HOST=api\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, "services", "web", ".env"), []byte("HOST=web\n"), 0600)
+
+	for _, project := range []string{"services/api", "services/web"} {
+		if _, err := InitNamedProject(project, []string{filepath.Join(project, ".env")}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := InitNamedProject("services/api", []string{"services/api/.env"}, false); err == nil || err.Code != 147 {
+		t.Fatalf("Expected code %d, but found %v", 147, err)
+	}
+
+	os.Chdir(filepath.Join(testingRoot, "services", "api"))
+	if location, err := Where(); err != nil || location.Project != "services/api" || location.Store != filepath.Join(testingRoot, ".jorge", projectsDirName, "services", "api") {
+		t.Fatalf("Unexpected location %v (%v)", location, err)
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, "services", "api", ".env"), []byte("HOST=staging-api\n"), 0600)
+	if _, err := UseConfigFile("staging", true); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Chdir(testingRoot)
+	if projects, err := UseAll("default"); err != nil || len(projects) != 2 {
+		t.Fatalf("Unexpected projects %v (%v)", projects, err)
+	}
+
+	if projects, err := UseAll("staging"); err != nil || len(projects) != 1 || projects[0] != "services/api" {
+		t.Fatalf("Unexpected projects %v (%v)", projects, err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, "services", "api", ".env")); string(data) != "HOST=staging-api\n" {
+		t.Fatalf("Unexpected working file %s", data)
+	}
+
+	SetProjectDir("services/web")
+	defer SetProjectDir("")
+	if config, err := getInternalConfig(); err != nil || config.CurrentEnv != "default" {
+		t.Fatalf("Unexpected config %v (%v)", config, err)
+	}
+}
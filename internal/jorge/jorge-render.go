@@ -0,0 +1,249 @@
+package jorge
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Formats that an environment can be rendered to
+const (
+	FormatDotenv       = "dotenv"
+	FormatJSON         = "json"
+	FormatYAML         = "yaml"
+	FormatK8sSecret    = "k8s-secret"
+	FormatK8sConfigMap = "k8s-configmap"
+	FormatDockerEnv    = "docker-env"
+	FormatSystemdEnv   = "systemd-env"
+	FormatShellExport  = "shell-export"
+)
+
+// RenderFormats lists the formats of `jorge render`
+var RenderFormats = []string{FormatDotenv, FormatJSON, FormatYAML, FormatK8sSecret, FormatK8sConfigMap, FormatDockerEnv, FormatSystemdEnv, FormatShellExport}
+
+var envVariableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var k8sKeyPattern = regexp.MustCompile(`^[-._A-Za-z0-9]+$`)
+var k8sNameInvalidChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// envEntries
+// Returns the keys of every tracked file of an environment, with the included
+// environments and the secrets resolved. A key that appears in several files
+// takes the value of the last one, and the keys that are missing get their
+// default value from the schema
+func envEntries(config JorgeConfig, envName string) ([]DotenvEntry, *EncapsulatedError) {
+	files, err := storedFileEntries(config, envName)
+	if err != nil {
+		return []DotenvEntry{}, err
+	}
+
+	entries := []DotenvEntry{}
+	indexes := map[string]int{}
+	for _, file := range files {
+		for _, entry := range file.entries {
+			if index, found := indexes[entry.Key]; found {
+				entries[index].Value = entry.Value
+				continue
+			}
+
+			indexes[entry.Key] = len(entries)
+			entries = append(entries, entry)
+		}
+	}
+
+	return append(entries, schemaDefaults(entries)...), nil
+}
+
+// renderError
+// Creates the error of a key or a value that a format can not hold
+func renderError(format string, key string, problem string) *EncapsulatedError {
+	encErr := EncapsulatedError{
+		OriginalErr: fmt.Errorf("%s can not be rendered as %s", key, format),
+		Message:     ErrorCode.Str(E143),
+		Solution:    SolutionMessage.Str(S135, format, key, problem),
+		Code:        143,
+	}
+	return &encErr
+}
+
+// quoteDotenvValue
+// Quotes a value for a dotenv file, when it needs quoting, with the escapes
+// that parseDotenv understands. Single quotes are preferred, since Compose and
+// python-dotenv expand variables in double quoted values
+func quoteDotenvValue(value string) string {
+	if len(value) > 0 && !strings.ContainsAny(value, " \t\n\r#\"'\\$`") {
+		return value
+	}
+
+	if !strings.ContainsAny(value, "\n\r'") {
+		return "'" + value + "'"
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// quoteSystemdValue
+// Quotes a value for an EnvironmentFile of systemd, which keeps the newlines
+// of double quoted values
+func quoteSystemdValue(value string) string {
+	if len(value) > 0 && !strings.ContainsAny(value, " \t\n\r#;\"'\\$`") {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// quotePosixValue
+// Quotes a value for a POSIX shell. Single quotes keep every character
+// literally, so only the single quotes themselves need escaping
+func quotePosixValue(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// k8sResourceName
+// Converts a name to a valid name of a Kubernetes resource
+func k8sResourceName(name string) string {
+	name = strings.Trim(k8sNameInvalidChars.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	if len(name) > 253 {
+		name = strings.Trim(name[:253], "-.")
+	}
+
+	if len(name) == 0 {
+		return "jorge"
+	}
+	return name
+}
+
+// renderEntries
+// Writes the entries of an environment in the given format. The resource name
+// is the name of the Kubernetes manifests
+func renderEntries(entries []DotenvEntry, format string, resourceName string) ([]byte, *EncapsulatedError) {
+	var builder strings.Builder
+
+	switch format {
+	case FormatDotenv:
+		for _, entry := range entries {
+			if strings.ContainsAny(entry.Key, "= \t\n#") {
+				return []byte{}, renderError(format, entry.Key, "the key contains whitespace, = or #")
+			}
+			fmt.Fprintf(&builder, "%s=%s\n", entry.Key, quoteDotenvValue(entry.Value))
+		}
+	case FormatDockerEnv:
+		// Docker reads every line literally, so neither quotes nor newlines can
+		// be written
+		for _, entry := range entries {
+			if strings.ContainsAny(entry.Key, "= \t\n") {
+				return []byte{}, renderError(format, entry.Key, "the key contains whitespace or =")
+			}
+			if strings.ContainsAny(entry.Value, "\n\r") {
+				return []byte{}, renderError(format, entry.Key, "the value spans multiple lines")
+			}
+			fmt.Fprintf(&builder, "%s=%s\n", entry.Key, entry.Value)
+		}
+	case FormatSystemdEnv, FormatShellExport:
+		for _, entry := range entries {
+			if !envVariableNamePattern.MatchString(entry.Key) {
+				return []byte{}, renderError(format, entry.Key, "the key is not a valid variable name")
+			}
+
+			if format == FormatSystemdEnv {
+				fmt.Fprintf(&builder, "%s=%s\n", entry.Key, quoteSystemdValue(entry.Value))
+			} else {
+				fmt.Fprintf(&builder, "export %s=%s\n", entry.Key, quotePosixValue(entry.Value))
+			}
+		}
+	case FormatJSON:
+		document := map[string]string{}
+		for _, entry := range entries {
+			document[entry.Key] = entry.Value
+		}
+
+		data, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return []byte{}, renderError(format, "the environment", err.Error())
+		}
+		builder.Write(data)
+		builder.WriteString("\n")
+	case FormatYAML, FormatK8sSecret, FormatK8sConfigMap:
+		document := yaml.MapSlice{}
+		for _, entry := range entries {
+			value := entry.Value
+
+			if format != FormatYAML {
+				if !k8sKeyPattern.MatchString(entry.Key) {
+					return []byte{}, renderError(format, entry.Key, "the key may only contain letters, digits, -, _ and .")
+				}
+				if format == FormatK8sSecret {
+					value = base64.StdEncoding.EncodeToString([]byte(value))
+				}
+			}
+
+			document = append(document, yaml.MapItem{Key: entry.Key, Value: value})
+		}
+
+		if format != FormatYAML {
+			kind := "ConfigMap"
+			if format == FormatK8sSecret {
+				kind = "Secret"
+			}
+
+			manifest := yaml.MapSlice{
+				{Key: "apiVersion", Value: "v1"},
+				{Key: "kind", Value: kind},
+				{Key: "metadata", Value: yaml.MapSlice{{Key: "name", Value: k8sResourceName(resourceName)}}},
+			}
+			if format == FormatK8sSecret {
+				manifest = append(manifest, yaml.MapItem{Key: "type", Value: "Opaque"})
+			}
+			document = append(manifest, yaml.MapItem{Key: "data", Value: document})
+		}
+
+		data, err := yaml.Marshal(document)
+		if err != nil {
+			return []byte{}, renderError(format, "the environment", err.Error())
+		}
+		builder.Write(data)
+	default:
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("unknown format %s", format),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S109, format, strings.Join(RenderFormats, ", ")),
+			Code:        118,
+		}
+		return []byte{}, &encErr
+	}
+
+	return []byte(builder.String()), nil
+}
+
+// RenderEnv
+// Converts the stored files of an environment to another format without
+// using the environment. An empty environment name selects the current
+// environment, and an empty resource name names the Kubernetes manifests after
+// the environment
+func RenderEnv(envName string, format string, resourceName string) ([]byte, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if len(envName) == 0 {
+		envName = config.CurrentEnv
+	}
+
+	if len(resourceName) == 0 {
+		resourceName = envName
+	}
+
+	entries, err := envEntries(config, envName)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return renderEntries(entries, format, resourceName)
+}
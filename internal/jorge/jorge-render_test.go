@@ -0,0 +1,42 @@
+package jorge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenderEntries(t *testing.T) {
+	entries := []DotenvEntry{{Key: "HOST", Value: "local host"}, {Key: "MESSAGE", Value: "it's\n\"quoted\" #1"}}
+
+	data, err := renderEntries(entries, FormatDotenv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed := parseDotenv(data); !reflect.DeepEqual(parsed, entries) {
+		t.Fatalf("Rendered dotenv does not parse back: %s", data)
+	}
+
+	expanded := []DotenvEntry{{Key: "HOME", Value: "$HOME `id`"}, {Key: "MESSAGE", Value: "it's $HOME\r\n"}}
+	if data, err := renderEntries(expanded, FormatDotenv, ""); err != nil || string(data) != "HOME='$HOME `id`'\nMESSAGE=\"it's \\$HOME\\r\\n\"\n" {
+		t.Fatalf("Unexpected dotenv %q (%v)", data, err)
+	} else if parsed := parseDotenv(data); !reflect.DeepEqual(parsed, expanded) {
+		t.Fatalf("Rendered dotenv does not parse back: %s", data)
+	}
+
+	if data, err := renderEntries(entries, FormatShellExport, ""); err != nil || string(data) != "export HOST='local host'\nexport MESSAGE='it'\\''s\n\"quoted\" #1'\n" {
+		t.Fatalf("Unexpected shell export %q (%v)", data, err)
+	}
+
+	if data, err := renderEntries(entries[:1], FormatK8sSecret, "Staging_API"); err != nil || string(data) != "apiVersion: v1\nkind: Secret\nmetadata:\n  name: staging-api\ntype: Opaque\ndata:\n  HOST: bG9jYWwgaG9zdA==\n" {
+		t.Fatalf("Unexpected secret manifest %q (%v)", data, err)
+	}
+
+	if _, err := renderEntries(entries, FormatDockerEnv, ""); err == nil || err.Code != 143 {
+		t.Fatalf("Expected code %d, but found %v", 143, err)
+	}
+
+	if _, err := renderEntries([]DotenvEntry{{Key: "database.host", Value: "db"}}, FormatSystemdEnv, ""); err == nil || err.Code != 143 {
+		t.Fatalf("Expected code %d, but found %v", 143, err)
+	}
+}
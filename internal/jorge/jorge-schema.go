@@ -0,0 +1,412 @@
+package jorge
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const schemaFileName = "schema.yml"
+
+// Types of the values that can be declared in the schema
+const (
+	SchemaString = "string"
+	SchemaInt    = "int"
+	SchemaBool   = "bool"
+	SchemaURL    = "url"
+	SchemaEnum   = "enum"
+)
+
+// JorgeSchema
+// The contents of .jorge/schema.yml. It declares the keys that the
+// environments are expected to contain
+type JorgeSchema struct {
+	Keys map[string]SchemaKey `yaml:"keys"`
+}
+
+// SchemaKey
+// The rules of a single key. A key with a default value is never missing
+type SchemaKey struct {
+	Required bool     `yaml:"required,omitempty"`
+	Type     string   `yaml:"type,omitempty"`
+	Values   []string `yaml:"values,omitempty"`
+	Default  string   `yaml:"default,omitempty"`
+	Pattern  string   `yaml:"pattern,omitempty"`
+}
+
+// SchemaViolation
+// A key of an environment that does not match the schema
+type SchemaViolation struct {
+	Env     string `json:"env" yaml:"env"`
+	File    string `json:"file,omitempty" yaml:"file,omitempty"`
+	Key     string `json:"key" yaml:"key"`
+	Problem string `json:"problem" yaml:"problem"`
+}
+
+var validationEnabled = true
+
+// SetValidationEnabled
+// Enables or disables the schema validation of use and commit for the current
+// process. It is used by the `--no-validate` flag
+func SetValidationEnabled(enabled bool) {
+	validationEnabled = enabled
+}
+
+// getSchema
+// Returns the schema of the project. The second return value is false when
+// the project has no schema
+func getSchema() (JorgeSchema, bool, *EncapsulatedError) {
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return JorgeSchema{}, false, err
+	}
+
+	schemaFilePath := filepath.Join(jorgeDir, schemaFileName)
+	data, readErr := ioutil.ReadFile(schemaFilePath)
+	if errors.Is(readErr, os.ErrNotExist) {
+		return JorgeSchema{}, false, nil
+	} else if readErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E130),
+			Solution:    SolutionMessage.Str(S003, schemaFilePath),
+			Code:        130,
+		}
+		return JorgeSchema{}, false, &encErr
+	}
+
+	var schema JorgeSchema
+	if ymlErr := yaml.UnmarshalStrict(data, &schema); ymlErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: ymlErr,
+			Message:     ErrorCode.Str(E130),
+			Solution:    SolutionMessage.Str(S124, schemaFilePath, ymlErr),
+			Code:        130,
+		}
+		return JorgeSchema{}, false, &encErr
+	}
+
+	for key, rules := range schema.Keys {
+		switch rules.Type {
+		case "", SchemaString, SchemaInt, SchemaBool, SchemaURL:
+		case SchemaEnum:
+			if len(rules.Values) == 0 {
+				encErr := EncapsulatedError{
+					OriginalErr: fmt.Errorf("enum key %s has no values", key),
+					Message:     ErrorCode.Str(E130),
+					Solution:    SolutionMessage.Str(S124, schemaFilePath, fmt.Sprintf("declare the values of %s", key)),
+					Code:        130,
+				}
+				return JorgeSchema{}, false, &encErr
+			}
+		default:
+			encErr := EncapsulatedError{
+				OriginalErr: fmt.Errorf("unknown type %s of key %s", rules.Type, key),
+				Message:     ErrorCode.Str(E130),
+				Solution:    SolutionMessage.Str(S124, schemaFilePath, SolutionMessage.Str(S109, rules.Type, "string, int, bool, url, enum")),
+				Code:        130,
+			}
+			return JorgeSchema{}, false, &encErr
+		}
+
+		if _, regexErr := regexp.Compile(rules.Pattern); regexErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: regexErr,
+				Message:     ErrorCode.Str(E130),
+				Solution:    SolutionMessage.Str(S124, schemaFilePath, regexErr),
+				Code:        130,
+			}
+			return JorgeSchema{}, false, &encErr
+		}
+	}
+
+	return schema, true, nil
+}
+
+// checkValue
+// Returns the reason the value does not match the rules of its key, or an
+// empty string when it matches
+func (k SchemaKey) checkValue(value string) string {
+	switch k.Type {
+	case SchemaInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Sprintf("%q is not an integer", value)
+		}
+	case SchemaBool:
+		switch strings.ToLower(value) {
+		case "true", "false", "1", "0", "yes", "no", "on", "off":
+		default:
+			return fmt.Sprintf("%q is not a boolean", value)
+		}
+	case SchemaURL:
+		if parsed, err := url.Parse(value); err != nil || len(parsed.Scheme) == 0 || (len(parsed.Host) == 0 && len(parsed.Opaque) == 0) {
+			return fmt.Sprintf("%q is not a URL", value)
+		}
+	case SchemaEnum:
+		if !Contains(k.Values, value) {
+			return fmt.Sprintf("%q is not one of %s", value, strings.Join(k.Values, ", "))
+		}
+	}
+
+	if len(k.Pattern) > 0 {
+		if matched, _ := regexp.MatchString(k.Pattern, value); !matched {
+			return fmt.Sprintf("%q does not match %s", value, k.Pattern)
+		}
+	}
+
+	return ""
+}
+
+// configFileEntries
+// The keys of a configuration file, along with the name of the file
+type configFileEntries struct {
+	fileName string
+	entries  []DotenvEntry
+}
+
+// validateEntries
+// Checks the keys of the configuration files of an environment against the
+// schema. The keys of all the tracked files are checked together
+func validateEntries(schema JorgeSchema, envName string, files []configFileEntries) []SchemaViolation {
+	values := map[string]string{}
+	sources := map[string]string{}
+
+	for _, file := range files {
+		for _, entry := range file.entries {
+			values[entry.Key] = entry.Value
+			sources[entry.Key] = file.fileName
+		}
+	}
+
+	keys := []string{}
+	for key := range schema.Keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	violations := []SchemaViolation{}
+	for _, key := range keys {
+		rules := schema.Keys[key]
+
+		value, found := values[key]
+		if !found {
+			if rules.Required && len(rules.Default) == 0 {
+				violations = append(violations, SchemaViolation{Env: envName, Key: key, Problem: "required key is missing"})
+			}
+			continue
+		}
+
+		if problem := rules.checkValue(value); len(problem) > 0 {
+			violations = append(violations, SchemaViolation{Env: envName, File: sources[key], Key: key, Problem: problem})
+		}
+	}
+
+	return violations
+}
+
+// storedFileEntries
+// Returns the keys of the rendered configuration files of an environment
+func storedFileEntries(config JorgeConfig, envName string) ([]configFileEntries, *EncapsulatedError) {
+	envDir, err := getEnvDirPath(envName)
+	if err != nil {
+		return []configFileEntries{}, err
+	}
+
+	files := []configFileEntries{}
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+		storedFilePath := filepath.Join(envDir, trackedFileName)
+
+		data, readErr := ioutil.ReadFile(storedFilePath)
+		if readErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: readErr,
+				Message:     ErrorCode.Str(E107),
+				Solution:    SolutionMessage.Str(S003, storedFilePath),
+				Code:        107,
+			}
+			return []configFileEntries{}, &encErr
+		}
+
+		renderedData, _, err := renderConfigFile(envName, trackedFileName, data)
+		if err != nil {
+			return []configFileEntries{}, err
+		}
+
+		entries, ok := parseConfigEntries(trackedFileName, renderedData)
+		if !ok {
+			encErr := EncapsulatedError{
+				OriginalErr: fmt.Errorf("%s can not be parsed", storedFilePath),
+				Message:     ErrorCode.Str(E152),
+				Solution:    SolutionMessage.Str(S124, storedFilePath, "the file is not valid"),
+				Code:        152,
+			}
+			return []configFileEntries{}, &encErr
+		}
+		files = append(files, configFileEntries{fileName: trackedFileName, entries: entries})
+	}
+
+	return files, nil
+}
+
+// workingFileEntries
+// Returns the keys of the working configuration files
+func workingFileEntries(config JorgeConfig) ([]configFileEntries, *EncapsulatedError) {
+	projectRoot, err := resolveJorgeDir()
+	if err != nil {
+		return []configFileEntries{}, err
+	}
+
+	files := []configFileEntries{}
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+		workingFilePath := filepath.Join(projectRoot, trackedFile)
+
+		data, readErr := ioutil.ReadFile(workingFilePath)
+		if readErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: readErr,
+				Message:     ErrorCode.Str(E107),
+				Solution:    SolutionMessage.Str(S003, workingFilePath),
+				Code:        107,
+			}
+			return []configFileEntries{}, &encErr
+		}
+
+		entries, _ := parseConfigEntries(trackedFileName, data)
+		files = append(files, configFileEntries{fileName: trackedFileName, entries: entries})
+	}
+
+	return files, nil
+}
+
+// schemaViolationsError
+// Creates the error that blocks an operation, listing every violation
+func schemaViolationsError(violations []SchemaViolation) *EncapsulatedError {
+	lines := []string{}
+	for _, violation := range violations {
+		location := violation.Env
+		if len(violation.File) > 0 {
+			location = fmt.Sprintf("%s/%s", violation.Env, violation.File)
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s: %s", location, violation.Key, violation.Problem))
+	}
+
+	encErr := EncapsulatedError{
+		OriginalErr: fmt.Errorf("%d keys do not match the schema", len(violations)),
+		Message:     ErrorCode.Str(E129),
+		Solution:    SolutionMessage.Str(S123, strings.Join(lines, "\n")),
+		Code:        129,
+	}
+	return &encErr
+}
+
+// validateStoredFiles
+// Blocks using an environment whose rendered files do not match the schema
+func validateStoredFiles(config JorgeConfig, envName string) *EncapsulatedError {
+	if !validationEnabled {
+		return nil
+	}
+
+	schema, found, err := getSchema()
+	if err != nil || !found {
+		return err
+	}
+
+	files, err := storedFileEntries(config, envName)
+	if err != nil {
+		return err
+	}
+
+	if violations := validateEntries(schema, envName, files); len(violations) > 0 {
+		return schemaViolationsError(violations)
+	}
+
+	log.Debug(fmt.Sprintf("Env %s matches the schema", envName))
+	return nil
+}
+
+// validateWorkingFiles
+// Blocks storing working files that do not match the schema as an environment
+func validateWorkingFiles(config JorgeConfig, envName string) *EncapsulatedError {
+	if !validationEnabled {
+		return nil
+	}
+
+	schema, found, err := getSchema()
+	if err != nil || !found {
+		return err
+	}
+
+	files, err := workingFileEntries(config)
+	if err != nil {
+		return err
+	}
+
+	if violations := validateEntries(schema, envName, files); len(violations) > 0 {
+		return schemaViolationsError(violations)
+	}
+
+	return nil
+}
+
+// ValidateEnv
+// Checks the stored files of an environment against the schema of the
+// project. An empty environment name selects the current environment. A
+// project without a schema has no violations
+func ValidateEnv(envName string) ([]SchemaViolation, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return []SchemaViolation{}, err
+	}
+
+	if len(envName) == 0 {
+		envName = config.CurrentEnv
+	}
+
+	schema, found, err := getSchema()
+	if err != nil || !found {
+		return []SchemaViolation{}, err
+	}
+
+	files, err := storedFileEntries(config, envName)
+	if err != nil {
+		return []SchemaViolation{}, err
+	}
+
+	return validateEntries(schema, envName, files), nil
+}
+
+// schemaDefaults
+// Returns the default values of the keys that none of the entries contain
+func schemaDefaults(entries []DotenvEntry) []DotenvEntry {
+	schema, found, err := getSchema()
+	if err != nil || !found {
+		return []DotenvEntry{}
+	}
+
+	defined := map[string]bool{}
+	for _, entry := range entries {
+		defined[entry.Key] = true
+	}
+
+	defaults := []DotenvEntry{}
+	for key, rules := range schema.Keys {
+		if len(rules.Default) > 0 && !defined[key] {
+			defaults = append(defaults, DotenvEntry{Key: key, Value: rules.Default})
+		}
+	}
+
+	sort.Slice(defaults, func(i, j int) bool { return defaults[i].Key < defaults[j].Key })
+	return defaults
+}
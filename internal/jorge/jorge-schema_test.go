@@ -0,0 +1,93 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateEntries(t *testing.T) {
+	schema := JorgeSchema{Keys: map[string]SchemaKey{
+		"DATABASE_URL": {Required: true, Type: SchemaURL},
+		"PORT":         {Required: true, Type: SchemaInt, Default: "8080"},
+		"DEBUG":        {Type: SchemaBool},
+		"LOG_LEVEL":    {Type: SchemaEnum, Values: []string{"debug", "info"}},
+		"NAME":         {Pattern: "^[a-z]+$"},
+	}}
+
+	files := []configFileEntries{{
+		fileName: ".env",
+		entries:  parseDotenv([]byte("DEBUG=maybe\nLOG_LEVEL=info\nNAME=Jorge\n")),
+	}}
+
+	violations := validateEntries(schema, "mockEnv", files)
+
+	expected := map[string]bool{"DATABASE_URL": true, "DEBUG": true, "NAME": true}
+	if len(violations) != len(expected) {
+		t.Fatalf("Expected violations of %v, but found %v", expected, violations)
+	}
+
+	for _, violation := range violations {
+		if !expected[violation.Key] {
+			t.Fatalf("Unexpected violation %v", violation)
+		}
+	}
+}
+
+func TestValidateJsonNumbers(t *testing.T) {
+	schema := JorgeSchema{Keys: map[string]SchemaKey{
+		"database.port": {Required: true, Type: SchemaInt},
+	}}
+
+	entries, ok := parseConfigEntries("config.json", []byte(`{"database": {"port": 1000000, "id": 12345678901234567890}}`))
+	if !ok || len(entries) != 2 || entries[0].Value != "12345678901234567890" || entries[1].Value != "1000000" {
+		t.Fatalf("Unexpected entries %v", entries)
+	}
+
+	if violations := validateEntries(schema, "mockEnv", []configFileEntries{{fileName: "config.json", entries: entries}}); len(violations) != 0 {
+		t.Fatalf("Unexpected violations %v", violations)
+	}
+}
+
+func TestUseIsBlockedBySchema(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.Remove(filepath.Join(testingRoot, "config.json"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: config.json\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "schema.yml"), []byte("keys:\n  database.port:\n    required: true\n    type: int\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "config.json"), []byte(`{"database": {"port": "none"}}`), 0600)
+
+	if _, err := UseConfigFile("mockEnv", false); err == nil || err.Code != 129 {
+		t.Fatalf("Expected code %d, but found %v", 129, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(testingRoot, "config.json")); err == nil {
+		t.Fatal("Configuration file was written although the validation failed")
+	}
+
+	SetValidationEnabled(false)
+	defer SetValidationEnabled(true)
+
+	if _, err := UseConfigFile("mockEnv", false); err != nil {
+		t.Fatal(err)
+	}
+
+	SetValidationEnabled(true)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "config.json"), []byte(`{"database": {"port": 5432}}`), 0600)
+
+	if violations, err := ValidateEnv("mockEnv"); err != nil || len(violations) != 0 {
+		t.Fatalf("Unexpected violations %v (%v)", violations, err)
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "config.json"), []byte(`{"database": `), 0600)
+
+	if _, err := ValidateEnv("mockEnv"); err == nil || err.Code != 152 {
+		t.Fatalf("Expected code %d, but found %v", 152, err)
+	}
+}
@@ -0,0 +1,761 @@
+package jorge
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultSecretProvider = "keyfile"
+const keyfileName = "secrets.enc"
+const secretKeyFileName = "secrets.key"
+const secretRefsFileName = "secret-refs.yml"
+const secretRefsKeyFileName = "secret-refs.key"
+
+// secretRefPattern matches the references to secrets in the stored files, e.g.
+// ${secret:stripe-key} or ${secret:pass:web/stripe-key}
+var secretRefPattern = regexp.MustCompile(`\$\{secret:([^}]+)\}`)
+
+// JorgeSecrets
+// The `secrets` key of config.yml. Provider is the provider of the references
+// that do not name one and KeyPath the key of the keyfile provider
+type JorgeSecrets struct {
+	Provider string `yaml:"provider,omitempty"`
+	Keyfile  string `yaml:"keyfile,omitempty"`
+	KeyPath  string `yaml:"keyPath,omitempty"`
+	Command  string `yaml:"command,omitempty"`
+}
+
+// SecretProvider
+// A backend that returns the value of a secret by its name
+type SecretProvider interface {
+	Resolve(name string) (string, error)
+}
+
+// SecretStore
+// A SecretProvider that can also store secrets, so that they can be managed
+// with `jorge secret`
+type SecretStore interface {
+	SecretProvider
+	SetSecret(name string, value string) error
+	RemoveSecret(name string) error
+	ListSecrets() ([]string, error)
+}
+
+// SecretProviderFactory
+// Creates a provider from the secrets configuration of the project
+type SecretProviderFactory func(config JorgeSecrets) (SecretProvider, error)
+
+var secretProviders = map[string]SecretProviderFactory{
+	"keyfile": newKeyfileProvider,
+	"pass":    newPassProvider,
+	"exec":    newExecProvider,
+}
+
+// RegisterSecretProvider
+// Makes a provider available to the secret references under the given name.
+// Registering an existing name replaces the provider
+func RegisterSecretProvider(name string, factory SecretProviderFactory) {
+	secretProviders[name] = factory
+}
+
+// secretProviderNames
+// Returns the names of the registered providers
+func secretProviderNames() []string {
+	names := []string{}
+	for name := range secretProviders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// getSecretProvider
+// Creates the provider registered under the name. An empty name returns the
+// default provider of the configuration
+func getSecretProvider(config JorgeSecrets, name string) (SecretProvider, *EncapsulatedError) {
+	if len(name) == 0 {
+		name = config.Provider
+	}
+
+	if len(name) == 0 {
+		name = defaultSecretProvider
+	}
+
+	factory, found := secretProviders[name]
+	if !found {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("unknown secret provider %s", name),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S109, name, strings.Join(secretProviderNames(), ", ")),
+			Code:        118,
+		}
+		return nil, &encErr
+	}
+
+	provider, err := factory(config)
+	if err != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: err,
+			Message:     ErrorCode.Str(E125),
+			Solution:    SolutionMessage.Str(S122, name),
+			Code:        125,
+		}
+		return nil, &encErr
+	}
+
+	return provider, nil
+}
+
+// getSecretStore
+// Returns the provider registered under the name when it can store secrets
+func getSecretStore(config JorgeSecrets, name string) (SecretStore, *EncapsulatedError) {
+	provider, err := getSecretProvider(config, name)
+	if err != nil {
+		return nil, err
+	}
+
+	store, ok := provider.(SecretStore)
+	if !ok {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("secret provider %s can not store secrets", name),
+			Message:     ErrorCode.Str(E126),
+			Solution:    SolutionMessage.Str(S119, name),
+			Code:        126,
+		}
+		return nil, &encErr
+	}
+
+	return store, nil
+}
+
+// getSecretsConfig
+// Returns the secrets configuration of the current project. Outside of a jorge
+// project the default configuration is used
+func getSecretsConfig() JorgeSecrets {
+	if _, err := resolveJorgeDir(); err != nil {
+		return JorgeSecrets{}
+	}
+
+	config, err := getInternalConfig()
+	if err != nil {
+		log.Debug(fmt.Sprintf("Using the default secrets configuration: %s", err.Message))
+		return JorgeSecrets{}
+	}
+
+	return config.Secrets
+}
+
+// secretResolver
+// Resolves the secret references of a file, creating every provider once
+type secretResolver struct {
+	config    JorgeSecrets
+	providers map[string]SecretProvider
+}
+
+// resolve
+// Returns the value of a reference, which is either a secret name of the
+// default provider or <provider>:<name>
+func (r *secretResolver) resolve(ref string) (string, *EncapsulatedError) {
+	providerName, name := "", ref
+	if index := strings.Index(ref, ":"); index > 0 {
+		if _, found := secretProviders[ref[:index]]; found {
+			providerName, name = ref[:index], ref[index+1:]
+		}
+	}
+
+	provider, found := r.providers[providerName]
+	if !found {
+		var err *EncapsulatedError
+		if provider, err = getSecretProvider(r.config, providerName); err != nil {
+			return "", err
+		}
+		r.providers[providerName] = provider
+	}
+
+	value, resolveErr := provider.Resolve(name)
+	if resolveErr != nil {
+		if len(providerName) == 0 {
+			providerName = r.config.Provider
+		}
+		if len(providerName) == 0 {
+			providerName = defaultSecretProvider
+		}
+
+		encErr := EncapsulatedError{
+			OriginalErr: resolveErr,
+			Message:     ErrorCode.Str(E125),
+			Solution:    SolutionMessage.Str(S118, name, providerName),
+			Code:        125,
+		}
+		return "", &encErr
+	}
+
+	return value, nil
+}
+
+// secretLineHash
+// Identifies a rendered line without keeping the secret it contains. The line
+// is hashed with the random key of the store, so that the secrets can not be
+// guessed from secret-refs.yml alone
+func secretLineHash(key []byte, line []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(line)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// legacySecretLineHash
+// Identifies a rendered line the way stores written before the lines were
+// hashed with a key did, so that their references can still be restored
+func legacySecretLineHash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// getSecretRefsKey
+// Returns the key that the lines of secret-refs.yml are hashed with. When
+// create is set, a missing key is generated
+func getSecretRefsKey(create bool) ([]byte, error) {
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return nil, err.OriginalErr
+	}
+
+	keyPath := filepath.Join(jorgeDir, secretRefsKeyFileName)
+	key, readErr := ioutil.ReadFile(keyPath)
+	if errors.Is(readErr, os.ErrNotExist) && create {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+
+		return key, ioutil.WriteFile(keyPath, key, privateFileMode)
+	}
+
+	return key, readErr
+}
+
+// resolveSecrets
+// Replaces the secret references of the contents with their values. Along with
+// the rendered contents it returns the original lines that contained
+// references, indexed by the rendered line
+func resolveSecrets(config JorgeSecrets, data []byte) ([]byte, map[string]string, *EncapsulatedError) {
+	refs := map[string]string{}
+
+	if !secretRefPattern.Match(data) {
+		return data, refs, nil
+	}
+
+	resolver := secretResolver{config: config, providers: map[string]SecretProvider{}}
+
+	var rendered bytes.Buffer
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		content := bytes.TrimRight(line, "\r\n")
+
+		if !secretRefPattern.Match(content) {
+			rendered.Write(line)
+			continue
+		}
+
+		var resolveErr *EncapsulatedError
+		renderedContent := secretRefPattern.ReplaceAllFunc(content, func(match []byte) []byte {
+			if resolveErr != nil {
+				return match
+			}
+
+			value, err := resolver.resolve(string(secretRefPattern.FindSubmatch(match)[1]))
+			if err != nil {
+				resolveErr = err
+				return match
+			}
+			return []byte(value)
+		})
+
+		if resolveErr != nil {
+			return data, refs, resolveErr
+		}
+
+		refs[string(renderedContent)] = string(content)
+		rendered.Write(renderedContent)
+		rendered.Write(line[len(content):])
+	}
+
+	return rendered.Bytes(), refs, nil
+}
+
+// getSecretRefs
+// Returns the secret references that were resolved when the tracked files were
+// written, indexed by the name of the file
+func getSecretRefs() map[string]map[string]string {
+	refs := map[string]map[string]string{}
+
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return refs
+	}
+
+	data, readErr := ioutil.ReadFile(filepath.Join(jorgeDir, secretRefsFileName))
+	if readErr != nil {
+		return refs
+	}
+
+	if ymlErr := yaml.Unmarshal(data, &refs); ymlErr != nil {
+		log.Warn(fmt.Sprintf("Could not read %s: %v", secretRefsFileName, ymlErr))
+	}
+
+	return refs
+}
+
+// setSecretRefs
+// Records the secret references that were resolved in a tracked file, so that
+// the references and not the values are stored when the file is committed.
+// The rendered lines are recorded by their hash
+func setSecretRefs(fileName string, fileRefs map[string]string) *EncapsulatedError {
+	refs := getSecretRefs()
+
+	if _, found := refs[fileName]; !found && len(fileRefs) == 0 {
+		return nil
+	}
+
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return err
+	}
+
+	var ymlErr error
+	if len(fileRefs) == 0 {
+		delete(refs, fileName)
+	} else {
+		var key []byte
+		if key, ymlErr = getSecretRefsKey(true); ymlErr == nil {
+			hashedRefs := map[string]string{}
+			for renderedLine, original := range fileRefs {
+				hashedRefs[secretLineHash(key, []byte(renderedLine))] = original
+			}
+			refs[fileName] = hashedRefs
+		}
+	}
+
+	var data []byte
+	if ymlErr == nil {
+		data, ymlErr = yaml.Marshal(refs)
+	}
+	if ymlErr == nil {
+		ymlErr = ioutil.WriteFile(filepath.Join(jorgeDir, secretRefsFileName), data, privateFileMode)
+	}
+
+	if ymlErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: ymlErr,
+			Message:     ErrorCode.Str(E007),
+			Solution:    SolutionMessage.Str(S103, GetUser()),
+			Code:        7,
+		}
+		return &encErr
+	}
+
+	return nil
+}
+
+// restoreSecretRefs
+// Replaces the lines of a tracked file that still hold the resolved values of
+// secrets with the references they were rendered from. Edited lines are kept
+// as they are
+func restoreSecretRefs(fileName string, data []byte) []byte {
+	fileRefs := getSecretRefs()[fileName]
+
+	if len(fileRefs) == 0 {
+		return data
+	}
+
+	key, keyErr := getSecretRefsKey(false)
+	if keyErr != nil && !errors.Is(keyErr, os.ErrNotExist) {
+		log.Warn(fmt.Sprintf("Could not read %s: %v", secretRefsKeyFileName, keyErr))
+	}
+
+	var restored bytes.Buffer
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		content := bytes.TrimRight(line, "\r\n")
+
+		var original string
+		found := false
+		if keyErr == nil {
+			original, found = fileRefs[secretLineHash(key, content)]
+		}
+		if !found {
+			original, found = fileRefs[legacySecretLineHash(content)]
+		}
+
+		if found {
+			restored.WriteString(original)
+			restored.Write(line[len(content):])
+		} else {
+			restored.Write(line)
+		}
+	}
+
+	return restored.Bytes()
+}
+
+// SetSecret
+// Stores a secret in a provider that supports storing secrets. An empty
+// provider name selects the default provider of the project
+func SetSecret(providerName string, name string, value string) *EncapsulatedError {
+	config := getSecretsConfig()
+
+	store, err := getSecretStore(config, providerName)
+	if err != nil {
+		return err
+	}
+
+	if setErr := store.SetSecret(name, value); setErr != nil {
+		return keyfileError(config, setErr)
+	}
+
+	return nil
+}
+
+// RemoveSecret
+// Deletes a secret from a provider that supports storing secrets
+func RemoveSecret(providerName string, name string) *EncapsulatedError {
+	config := getSecretsConfig()
+
+	store, err := getSecretStore(config, providerName)
+	if err != nil {
+		return err
+	}
+
+	if removeErr := store.RemoveSecret(name); removeErr != nil {
+		return keyfileError(config, removeErr)
+	}
+
+	return nil
+}
+
+// ListSecrets
+// Returns the names of the secrets of a provider that supports storing secrets
+func ListSecrets(providerName string) ([]string, *EncapsulatedError) {
+	config := getSecretsConfig()
+
+	store, err := getSecretStore(config, providerName)
+	if err != nil {
+		return []string{}, err
+	}
+
+	names, listErr := store.ListSecrets()
+	if listErr != nil {
+		return []string{}, keyfileError(config, listErr)
+	}
+
+	return names, nil
+}
+
+// keyfileError
+// Wraps an error of the keyfile provider
+func keyfileError(config JorgeSecrets, err error) *EncapsulatedError {
+	path, keyPath := config.Keyfile, config.KeyPath
+	if provider, providerErr := newKeyfileProvider(config); providerErr == nil {
+		path, keyPath = provider.(*keyfileProvider).path, provider.(*keyfileProvider).keyPath
+	}
+
+	encErr := EncapsulatedError{
+		OriginalErr: err,
+		Message:     ErrorCode.Str(E127),
+		Solution:    SolutionMessage.Str(S120, path, keyPath),
+		Code:        127,
+	}
+	return &encErr
+}
+
+// keyfileProvider
+// Keeps the secrets in a local file that is encrypted with AES-256-GCM. The key
+// is read from $JORGE_SECRETS_KEY (base64) or from keyPath, which defaults to
+// $XDG_CONFIG_HOME/jorge/secrets.key and is generated when the first secret is
+// stored. The key is never kept in the directory of the keyfile, since anyone
+// who can read the keyfile could read the key as well
+type keyfileProvider struct {
+	path    string
+	keyPath string
+}
+
+func newKeyfileProvider(config JorgeSecrets) (SecretProvider, error) {
+	path, keyPath := config.Keyfile, config.KeyPath
+
+	if len(path) == 0 {
+		globalStoreDir, err := getGlobalStoreDir()
+		if err != nil {
+			return nil, err.OriginalErr
+		}
+		path = filepath.Join(globalStoreDir, keyfileName)
+	}
+
+	if len(keyPath) == 0 {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		keyPath = filepath.Join(configDir, globalStoreDirName, secretKeyFileName)
+	}
+
+	if len(os.Getenv("JORGE_SECRETS_KEY")) == 0 && filepath.Dir(filepath.Clean(keyPath)) == filepath.Dir(filepath.Clean(path)) {
+		return nil, fmt.Errorf("the key %s is in the same directory as the keyfile %s, set secrets.keyPath to a path outside of it", keyPath, path)
+	}
+
+	return &keyfileProvider{path: path, keyPath: keyPath}, nil
+}
+
+// key
+// Returns the encryption key. When create is set, a missing key file is
+// generated. A key that older versions kept next to the keyfile is moved to
+// the key path
+func (p *keyfileProvider) key(create bool) ([]byte, error) {
+	if encodedKey := os.Getenv("JORGE_SECRETS_KEY"); len(encodedKey) > 0 {
+		return base64.StdEncoding.DecodeString(encodedKey)
+	}
+
+	key, err := ioutil.ReadFile(p.keyPath)
+	if !errors.Is(err, os.ErrNotExist) {
+		return key, err
+	}
+
+	legacyKey, legacyErr := ioutil.ReadFile(p.path + ".key")
+	if legacyErr == nil {
+		if err := p.writeKey(legacyKey); err != nil {
+			return nil, err
+		}
+
+		log.Warn(fmt.Sprintf("Moved the key of %s from %s to %s", p.path, p.path+".key", p.keyPath))
+		return legacyKey, os.Remove(p.path + ".key")
+	} else if !errors.Is(legacyErr, os.ErrNotExist) {
+		return nil, legacyErr
+	}
+
+	if !create {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return key, p.writeKey(key)
+}
+
+// writeKey
+// Writes the encryption key to the key path
+func (p *keyfileProvider) writeKey(key []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p.keyPath), privateDirMode); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(p.keyPath, key, privateFileMode)
+}
+
+// load
+// Decrypts the keyfile. A missing keyfile holds no secrets
+func (p *keyfileProvider) load() (map[string]string, error) {
+	secrets := map[string]string{}
+
+	data, err := ioutil.ReadFile(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return secrets, nil
+	} else if err != nil {
+		return secrets, err
+	}
+
+	key, err := p.key(false)
+	if err != nil {
+		return secrets, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return secrets, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return secrets, fmt.Errorf("%s is not a valid keyfile", p.path)
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return secrets, err
+	}
+
+	return secrets, yaml.Unmarshal(plaintext, &secrets)
+}
+
+// save
+// Encrypts the secrets with a fresh nonce and replaces the keyfile
+func (p *keyfileProvider) save(secrets map[string]string) error {
+	key, err := p.key(true)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := yaml.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.path), privateDirMode); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(p.path, gcm.Seal(nonce, nonce, plaintext, nil), privateFileMode)
+}
+
+func (p *keyfileProvider) Resolve(name string) (string, error) {
+	secrets, err := p.load()
+	if err != nil {
+		return "", err
+	}
+
+	value, found := secrets[name]
+	if !found {
+		return "", fmt.Errorf("secret %s does not exist in %s", name, p.path)
+	}
+
+	return value, nil
+}
+
+func (p *keyfileProvider) SetSecret(name string, value string) error {
+	secrets, err := p.load()
+	if err != nil {
+		return err
+	}
+
+	secrets[name] = value
+	return p.save(secrets)
+}
+
+func (p *keyfileProvider) RemoveSecret(name string) error {
+	secrets, err := p.load()
+	if err != nil {
+		return err
+	}
+
+	if _, found := secrets[name]; !found {
+		return fmt.Errorf("secret %s does not exist in %s", name, p.path)
+	}
+
+	delete(secrets, name)
+	return p.save(secrets)
+}
+
+func (p *keyfileProvider) ListSecrets() ([]string, error) {
+	secrets, err := p.load()
+	if err != nil {
+		return []string{}, err
+	}
+
+	names := []string{}
+	for name := range secrets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// newGCM
+// Creates the AES-GCM cipher of the keyfile
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the secrets key must be 32 bytes long, found %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// passProvider
+// Reads the secrets from the pass password store. The value is the first line
+// of the entry
+type passProvider struct{}
+
+func newPassProvider(config JorgeSecrets) (SecretProvider, error) {
+	return passProvider{}, nil
+}
+
+func (p passProvider) Resolve(name string) (string, error) {
+	var stderr bytes.Buffer
+
+	passCmd := exec.Command("pass", "show", name)
+	passCmd.Stderr = &stderr
+
+	output, err := passCmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pass show %s: %v %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimRight(strings.SplitN(string(output), "\n", 2)[0], "\r"), nil
+}
+
+// execProvider
+// Runs the command configured under secrets.command through the platform
+// shell with the name of the secret in $JORGE_SECRET_NAME and uses its output
+// as the value
+type execProvider struct {
+	command string
+}
+
+func newExecProvider(config JorgeSecrets) (SecretProvider, error) {
+	if len(config.Command) == 0 {
+		return nil, errors.New("secrets.command is not set in .jorge/config.yml")
+	}
+
+	return execProvider{command: config.Command}, nil
+}
+
+func (p execProvider) Resolve(name string) (string, error) {
+	var stdout bytes.Buffer
+
+	execCmd := hookCommand(p.command)
+	execCmd.Stdout = &stdout
+	execCmd.Stderr = os.Stderr
+	execCmd.Env = append(os.Environ(), "JORGE_SECRET_NAME="+name)
+
+	runErr, timedOut := runWithTimeout(execCmd, defaultHookTimeout)
+	if timedOut {
+		return "", fmt.Errorf("%s exceeded the timeout of %v", p.command, defaultHookTimeout)
+	} else if runErr != nil {
+		return "", fmt.Errorf("%s: %v", p.command, runErr)
+	}
+
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
@@ -0,0 +1,131 @@
+package jorge
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type stubSecretProvider map[string]string
+
+func (p stubSecretProvider) Resolve(name string) (string, error) {
+	if value, found := p[name]; found {
+		return value, nil
+	}
+	return "", errors.New("not found")
+}
+
+func TestSecretsAreResolvedOnUseAndRestoredOnCommit(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	RegisterSecretProvider("stub", func(config JorgeSecrets) (SecretProvider, error) {
+		return stubSecretProvider{"api-key": "s3cr3t"}, nil
+	})
+	defer delete(secretProviders, "stub")
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.Remove(filepath.Join(testingRoot, "mainTestConfig"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: mainTestConfig\nsecrets:\n  provider: stub\n"), 0600)
+
+	stored := "HOST=localhost\nAPI_KEY=${secret:api-key}\n"
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "mainTestConfig"), []byte(stored), 0600)
+
+	if _, err := setConfigAsMain(filepath.Join(testingRoot, "mainTestConfig"), "mockEnv"); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, "mainTestConfig")); string(data) != "HOST=localhost\nAPI_KEY=s3cr3t\n" {
+		t.Fatalf("Secret was not resolved: %s", data)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, ".jorge", secretRefsFileName)); strings.Contains(string(data), legacySecretLineHash([]byte("API_KEY=s3cr3t"))) {
+		t.Fatalf("Secret line was hashed without a key: %s", data)
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, "mainTestConfig"), []byte("HOST=example.com\nAPI_KEY=s3cr3t\n"), 0600)
+
+	if err := CommitCurrentEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "mainTestConfig")); string(data) != "HOST=example.com\nAPI_KEY=${secret:api-key}\n" {
+		t.Fatalf("Secret value was stored: %s", data)
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "mainTestConfig"), []byte("API_KEY=${secret:missing}\n"), 0600)
+	if _, err := setConfigAsMain(filepath.Join(testingRoot, "mainTestConfig"), "mockEnv"); err == nil || err.Code != 125 {
+		t.Fatalf("Expected code %d, but found %v", 125, err)
+	}
+}
+
+func TestKeyfileProvider(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	defer os.RemoveAll(filepath.Join(testingRoot, "keyfile"))
+	defer os.RemoveAll(filepath.Join(testingRoot, "keys"))
+	config := JorgeSecrets{Keyfile: filepath.Join(testingRoot, "keyfile", "secrets.enc"), KeyPath: filepath.Join(testingRoot, "keys", "secrets.key")}
+
+	if _, err := newKeyfileProvider(JorgeSecrets{Keyfile: config.Keyfile, KeyPath: config.Keyfile + ".key"}); err == nil {
+		t.Fatal("Accepted a key next to the keyfile")
+	}
+
+	// Keys that were kept next to the keyfile are moved to the key path
+	os.MkdirAll(filepath.Join(testingRoot, "keyfile"), 0700)
+	os.WriteFile(config.Keyfile+".key", []byte("0123456789abcdef0123456789abcdef"), 0600)
+
+	provider, _ := newKeyfileProvider(config)
+	store := provider.(SecretStore)
+
+	if err := store.SetSecret("api-key", "s3cr3t"); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(config.Keyfile); len(data) == 0 || string(data) == "api-key: s3cr3t\n" {
+		t.Fatal("Keyfile was not encrypted")
+	}
+
+	if _, err := os.Stat(config.Keyfile + ".key"); err == nil {
+		t.Fatal("Key was kept next to the keyfile")
+	}
+
+	if key, _ := os.ReadFile(config.KeyPath); string(key) != "0123456789abcdef0123456789abcdef" {
+		t.Fatalf("Key was not moved to %s", config.KeyPath)
+	}
+
+	if value, err := store.Resolve("api-key"); err != nil || value != "s3cr3t" {
+		t.Fatalf("Expected %s, but found %s (%v)", "s3cr3t", value, err)
+	}
+
+	if err := store.RemoveSecret("api-key"); err != nil {
+		t.Fatal(err)
+	}
+
+	if names, err := store.ListSecrets(); err != nil || len(names) != 0 {
+		t.Fatalf("Unexpected secrets %v (%v)", names, err)
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	entries := parseDotenv([]byte("# comment\nexport A=1\nB=\"two\\nlines\"\nC='$literal'\nD=value # comment\nnot a pair\n"))
+
+	expected := []DotenvEntry{{"A", "1"}, {"B", "two\nlines"}, {"C", "$literal"}, {"D", "value"}}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %v, but found %v", expected, entries)
+	}
+
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Fatalf("Expected %v, but found %v", expected[i], entries[i])
+		}
+	}
+}
@@ -0,0 +1,506 @@
+package jorge
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const snapshotsDirName = "snapshots"
+const snapshotExt = ".tar.gz"
+const automaticSnapshotPrefix = "auto-"
+const defaultSnapshotsKept = 10
+const snapshotTimeLayout = "20060102T150405"
+
+// snapshotFiles are the entries of the .jorge directory that a snapshot holds
+var snapshotFiles = []string{configFileName, schemaFileName, "envs"}
+
+// JorgeSnapshots
+// The `snapshots` key of config.yml. Keep is the number of automatic
+// snapshots that are kept, while Disabled turns them off
+type JorgeSnapshots struct {
+	Keep     int  `yaml:"keep,omitempty"`
+	Disabled bool `yaml:"disabled,omitempty"`
+}
+
+// Snapshot
+// An archive of the environments and of config.yml. Automatic snapshots are
+// taken before risky operations and are removed by the retention policy
+type Snapshot struct {
+	Name      string    `json:"name" yaml:"name"`
+	Automatic bool      `json:"automatic" yaml:"automatic"`
+	Size      int64     `json:"size" yaml:"size"`
+	Created   time.Time `json:"created" yaml:"created"`
+}
+
+// snapshotError
+// Wraps an error that occurred while writing a snapshot
+func snapshotError(err error) *EncapsulatedError {
+	encErr := EncapsulatedError{
+		OriginalErr: err,
+		Message:     ErrorCode.Str(E139),
+		Solution:    SolutionMessage.Str(S103, GetUser()),
+		Code:        139,
+	}
+	return &encErr
+}
+
+// validateSnapshotName
+// Makes sure that the name of a snapshot can be used as a file name and is
+// not mistaken for an automatic snapshot
+func validateSnapshotName(name string) *EncapsulatedError {
+	if len(name) == 0 || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, automaticSnapshotPrefix) {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("invalid snapshot name %q", name),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S131, name),
+			Code:        118,
+		}
+		return &encErr
+	}
+
+	return nil
+}
+
+// writeSnapshot
+// Archives the environments and config.yml of the .jorge directory found at
+// jorgeDir to .jorge/snapshots/<name>.tar.gz. The bases of the working copies
+// are left out, since the working copies are not part of the snapshot
+func writeSnapshot(jorgeDir string, name string) (string, error) {
+	snapshotsDir := filepath.Join(jorgeDir, snapshotsDirName)
+	if err := os.MkdirAll(snapshotsDir, privateDirMode); err != nil {
+		return "", err
+	}
+
+	snapshotPath := filepath.Join(snapshotsDir, name+snapshotExt)
+	snapshotFile, err := os.OpenFile(snapshotPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, privateFileMode)
+	if err != nil {
+		return "", err
+	}
+
+	gzipWriter := gzip.NewWriter(snapshotFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	archive := func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == baseDirName {
+			return filepath.SkipDir
+		}
+
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			log.Debug(fmt.Sprintf("Skipping %s while archiving, it is not a regular file", filePath))
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(jorgeDir, filePath)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relativePath)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tarWriter, file)
+		return err
+	}
+
+	for _, snapshotFileName := range snapshotFiles {
+		filePath := filepath.Join(jorgeDir, snapshotFileName)
+		if _, statErr := os.Stat(filePath); errors.Is(statErr, os.ErrNotExist) {
+			continue
+		}
+
+		if err = filepath.Walk(filePath, archive); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = tarWriter.Close()
+	}
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if closeErr := snapshotFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(snapshotPath)
+		return "", err
+	}
+
+	log.Debug(fmt.Sprintf("Wrote snapshot %s", snapshotPath))
+	return snapshotPath, nil
+}
+
+// takeAutomaticSnapshot
+// Snapshots the .jorge directory before a risky operation and applies the
+// retention policy to the automatic snapshots. The reason is recorded in the
+// name of the snapshot
+func takeAutomaticSnapshot(jorgeDir string, settings JorgeSnapshots, reason string) *EncapsulatedError {
+	if settings.Disabled {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s%s-%s", automaticSnapshotPrefix, time.Now().UTC().Format(snapshotTimeLayout+".000"), reason)
+	if _, err := writeSnapshot(jorgeDir, name); err != nil {
+		return snapshotError(err)
+	}
+	log.Debug(fmt.Sprintf("Took snapshot %s before %s", name, reason))
+
+	keep := settings.Keep
+	if keep <= 0 {
+		keep = defaultSnapshotsKept
+	}
+
+	snapshots, err := listSnapshots(jorgeDir)
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for _, snapshot := range snapshots {
+		if !snapshot.Automatic {
+			continue
+		}
+
+		if kept++; kept > keep {
+			if removeErr := os.Remove(filepath.Join(jorgeDir, snapshotsDirName, snapshot.Name+snapshotExt)); removeErr != nil {
+				log.Warn(fmt.Sprintf("Could not remove the old snapshot %s: %v", snapshot.Name, removeErr))
+			}
+		}
+	}
+
+	return nil
+}
+
+// listSnapshots
+// Returns the snapshots of the .jorge directory found at jorgeDir, the most
+// recent first
+func listSnapshots(jorgeDir string) ([]Snapshot, *EncapsulatedError) {
+	entries, readErr := ioutil.ReadDir(filepath.Join(jorgeDir, snapshotsDirName))
+	if errors.Is(readErr, os.ErrNotExist) {
+		return []Snapshot{}, nil
+	} else if readErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E103),
+			Solution:    SolutionMessage.Str(S102),
+			Code:        103,
+		}
+		return []Snapshot{}, &encErr
+	}
+
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || !strings.HasSuffix(entry.Name(), snapshotExt) {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), snapshotExt)
+		snapshots = append(snapshots, Snapshot{
+			Name:      name,
+			Automatic: strings.HasPrefix(name, automaticSnapshotPrefix),
+			Size:      entry.Size(),
+			Created:   entry.ModTime(),
+		})
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if !snapshots[i].Created.Equal(snapshots[j].Created) {
+			return snapshots[i].Created.After(snapshots[j].Created)
+		}
+		return snapshots[i].Name > snapshots[j].Name
+	})
+
+	return snapshots, nil
+}
+
+// ListSnapshots
+// Returns the snapshots of the project, the most recent first
+func ListSnapshots() ([]Snapshot, *EncapsulatedError) {
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return []Snapshot{}, err
+	}
+
+	return listSnapshots(jorgeDir)
+}
+
+// CreateSnapshot
+// Archives every environment and config.yml under .jorge/snapshots. An empty
+// name names the snapshot after the current time
+func CreateSnapshot(name string) (Snapshot, *EncapsulatedError) {
+	if _, err := getInternalConfig(); err != nil {
+		return Snapshot{}, err
+	}
+
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	if len(name) == 0 {
+		name = time.Now().UTC().Format(snapshotTimeLayout)
+	} else if err := validateSnapshotName(name); err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshotPath, writeErr := writeSnapshot(jorgeDir, name)
+	if errors.Is(writeErr, os.ErrExist) {
+		encErr := EncapsulatedError{
+			OriginalErr: writeErr,
+			Message:     ErrorCode.Str(E142),
+			Solution:    SolutionMessage.Str(S134, name),
+			Code:        142,
+		}
+		return Snapshot{}, &encErr
+	} else if writeErr != nil {
+		return Snapshot{}, snapshotError(writeErr)
+	}
+
+	snapshot := Snapshot{Name: name, Created: time.Now()}
+	if info, statErr := os.Stat(snapshotPath); statErr == nil {
+		snapshot.Size = info.Size()
+		snapshot.Created = info.ModTime()
+	}
+
+	return snapshot, nil
+}
+
+// getSnapshotPath
+// Returns the path to the archive of an existing snapshot
+func getSnapshotPath(jorgeDir string, name string) (string, *EncapsulatedError) {
+	snapshotPath := filepath.Join(jorgeDir, snapshotsDirName, name+snapshotExt)
+
+	if len(name) == 0 || strings.ContainsAny(name, `/\`) {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("invalid snapshot name %q", name),
+			Message:     ErrorCode.Str(E140),
+			Solution:    SolutionMessage.Str(S132),
+			Code:        140,
+		}
+		return "", &encErr
+	}
+
+	if _, statErr := os.Stat(snapshotPath); statErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: statErr,
+			Message:     ErrorCode.Str(E140),
+			Solution:    SolutionMessage.Str(S132),
+			Code:        140,
+		}
+		return "", &encErr
+	}
+
+	return snapshotPath, nil
+}
+
+// extractSnapshot
+// Extracts the archive of a snapshot to a directory. Only the entries that a
+// snapshot can hold are accepted
+func extractSnapshot(snapshotPath string, destination string) error {
+	snapshotFile, err := os.Open(snapshotPath)
+	if err != nil {
+		return err
+	}
+	defer snapshotFile.Close()
+
+	gzipReader, err := gzip.NewReader(snapshotFile)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	foundConfig := false
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		root := strings.SplitN(name, "/", 2)[0]
+		if path.IsAbs(name) || strings.HasPrefix(name, "..") || !Contains(snapshotFiles, root) {
+			return fmt.Errorf("unexpected entry %s", header.Name)
+		}
+
+		target := filepath.Join(destination, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, privateDirMode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), privateDirMode); err != nil {
+				return err
+			}
+
+			file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, header.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+
+			_, copyErr := io.Copy(file, tarReader)
+			if closeErr := file.Close(); copyErr == nil {
+				copyErr = closeErr
+			}
+			if copyErr != nil {
+				return copyErr
+			}
+
+			foundConfig = foundConfig || name == configFileName
+		default:
+			return fmt.Errorf("unexpected entry %s", header.Name)
+		}
+	}
+
+	if !foundConfig {
+		return fmt.Errorf("%s is missing", configFileName)
+	}
+
+	return nil
+}
+
+// RestoreSnapshot
+// Replaces every environment and config.yml with the ones of a snapshot. The
+// current state is snapshotted first, so that the restore can be undone. The
+// working configuration files are not changed
+func RestoreSnapshot(name string) *EncapsulatedError {
+	config, err := getInternalConfig()
+	if err != nil {
+		return err
+	}
+
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return err
+	}
+
+	snapshotPath, err := getSnapshotPath(jorgeDir, name)
+	if err != nil {
+		return err
+	}
+
+	extractDir, tempErr := ioutil.TempDir(filepath.Join(jorgeDir, snapshotsDirName), ".restore-")
+	if tempErr != nil {
+		return snapshotError(tempErr)
+	}
+	defer os.RemoveAll(extractDir)
+
+	if extractErr := extractSnapshot(snapshotPath, extractDir); extractErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: extractErr,
+			Message:     ErrorCode.Str(E141),
+			Solution:    SolutionMessage.Str(S133, snapshotPath, extractErr),
+			Code:        141,
+		}
+		return &encErr
+	}
+
+	if err := takeAutomaticSnapshot(jorgeDir, config.Snapshots, "restore"); err != nil {
+		return err
+	}
+
+	// The entries are replaced one at a time, so a failure rolls back the
+	// ones that were already replaced
+	tx, err := beginTransaction(jorgeDir, "snapshot")
+	if err != nil {
+		return err
+	}
+	defer tx.close()
+
+	for _, snapshotFileName := range snapshotFiles {
+		if err := tx.track(filepath.Join(jorgeDir, snapshotFileName)); err != nil {
+			return err
+		}
+	}
+
+	for _, snapshotFileName := range snapshotFiles {
+		restoreErr := os.RemoveAll(filepath.Join(jorgeDir, snapshotFileName))
+
+		extractedPath := filepath.Join(extractDir, snapshotFileName)
+		if _, statErr := os.Stat(extractedPath); restoreErr == nil && statErr == nil {
+			restoreErr = os.Rename(extractedPath, filepath.Join(jorgeDir, snapshotFileName))
+		}
+
+		if restoreErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: restoreErr,
+				Message:     ErrorCode.Str(E141),
+				Solution:    SolutionMessage.Str(S103, GetUser()),
+				Code:        141,
+			}
+			return &encErr
+		}
+	}
+
+	tx.commit()
+	log.Debug(fmt.Sprintf("Restored snapshot %s", snapshotPath))
+	return nil
+}
+
+// RemoveSnapshot
+// Deletes a snapshot
+func RemoveSnapshot(name string) *EncapsulatedError {
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return err
+	}
+
+	snapshotPath, err := getSnapshotPath(jorgeDir, name)
+	if err != nil {
+		return err
+	}
+
+	if removeErr := os.Remove(snapshotPath); removeErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: removeErr,
+			Message:     ErrorCode.Str(E010),
+			Solution:    SolutionMessage.Str(S103, GetUser()),
+			Code:        10,
+		}
+		return &encErr
+	}
+
+	return nil
+}
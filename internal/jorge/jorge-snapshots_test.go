@@ -0,0 +1,68 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRestoresRemovedEnv(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "default"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "staging"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\nsnapshots:\n  keep: 1\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env"), []byte("HOST=localhost\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env"), []byte("HOST=staging\n"), 0600)
+
+	if _, err := CreateSnapshot("before-cleanup"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CreateSnapshot("before-cleanup"); err == nil || err.Code != 142 {
+		t.Fatalf("Expected code %d, but found %v", 142, err)
+	}
+
+	if err := RemoveEnv("staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RestoreSnapshot("before-cleanup"); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env")); err != nil || string(data) != "HOST=staging\n" {
+		t.Fatalf("Environment was not restored: %s (%v)", data, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(testingRoot, ".jorge", journalDirName)); err == nil {
+		t.Fatal("The journal of the restore was kept")
+	}
+
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot taken before the restore replaced the one taken before rm
+	automatic := []string{}
+	for _, snapshot := range snapshots {
+		if snapshot.Automatic {
+			automatic = append(automatic, snapshot.Name)
+		}
+	}
+
+	if len(snapshots) != 2 || len(automatic) != 1 || !strings.HasSuffix(automatic[0], "-restore") {
+		t.Fatalf("Unexpected snapshots %v", snapshots)
+	}
+
+	if err := RestoreSnapshot("missing"); err == nil || err.Code != 140 {
+		t.Fatalf("Expected code %d, but found %v", 140, err)
+	}
+}
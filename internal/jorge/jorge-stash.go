@@ -0,0 +1,311 @@
+package jorge
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const stashDirName = "stash"
+const stashMetaFileName = "stash.yml"
+
+// StashEntry
+// A parked version of the working configuration files. Index 0 is the most
+// recent entry
+type StashEntry struct {
+	Index   int       `json:"index" yaml:"-"`
+	Env     string    `json:"env" yaml:"env"`
+	Message string    `json:"message,omitempty" yaml:"message,omitempty"`
+	Created time.Time `json:"created" yaml:"created"`
+	dir     string
+}
+
+// getStashDir
+// Returns the path to the directory that holds the stash entries
+func getStashDir() (string, *EncapsulatedError) {
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(jorgeDir, stashDirName), nil
+}
+
+// stashError
+// Wraps a filesystem error of the stash
+func stashError(err error) *EncapsulatedError {
+	encErr := EncapsulatedError{
+		OriginalErr: err,
+		Message:     ErrorCode.Str(E135),
+		Solution:    SolutionMessage.Str(S103, GetUser()),
+		Code:        135,
+	}
+	return &encErr
+}
+
+// StashList
+// Returns the stash entries, the most recent first
+func StashList() ([]StashEntry, *EncapsulatedError) {
+	stashDir, err := getStashDir()
+	if err != nil {
+		return []StashEntry{}, err
+	}
+
+	dirs, readErr := ioutil.ReadDir(stashDir)
+	if errors.Is(readErr, os.ErrNotExist) {
+		return []StashEntry{}, nil
+	} else if readErr != nil {
+		return []StashEntry{}, stashError(readErr)
+	}
+
+	// The directories are named after their creation time, so that the
+	// reverse order of their names is the order of the stack
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name() > dirs[j].Name() })
+
+	entries := []StashEntry{}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		entryDir := filepath.Join(stashDir, dir.Name())
+		var entry StashEntry
+
+		data, readErr := ioutil.ReadFile(filepath.Join(entryDir, stashMetaFileName))
+		if readErr == nil {
+			readErr = yaml.Unmarshal(data, &entry)
+		}
+		if readErr != nil {
+			log.Warn(fmt.Sprintf("Skipping stash entry %s: %v", entryDir, readErr))
+			continue
+		}
+
+		entry.Index = len(entries)
+		entry.dir = entryDir
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// getStashEntry
+// Returns the stash entry with the given index
+func getStashEntry(index int) (StashEntry, *EncapsulatedError) {
+	entries, err := StashList()
+	if err != nil {
+		return StashEntry{}, err
+	}
+
+	if index < 0 || index >= len(entries) {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("stash@{%d} does not exist", index),
+			Message:     ErrorCode.Str(E133),
+			Solution:    SolutionMessage.Str(S127),
+			Code:        133,
+		}
+		return StashEntry{}, &encErr
+	}
+
+	return entries[index], nil
+}
+
+// workingCopyChanged
+// Determines whether the working files hold changes that are not stored in
+// the current environment. The working files of an environment that has not
+// been committed are always changed
+func workingCopyChanged(config JorgeConfig) bool {
+	envDir, err := getEnvDirPath(config.CurrentEnv)
+	if err != nil {
+		return true
+	}
+
+	return isWorkingCopyDirty(config, envDir)
+}
+
+// StashPush
+// Parks the changes of the working configuration files and restores them to
+// the stored version of the current environment. The files are stashed with
+// their secret references and without the included environments
+func StashPush(message string) (StashEntry, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return StashEntry{}, err
+	}
+
+	projectRoot, err := resolveJorgeDir()
+	if err != nil {
+		return StashEntry{}, err
+	}
+
+	if !workingCopyChanged(config) {
+		encErr := EncapsulatedError{
+			OriginalErr: ErrorCode.Err(E132),
+			Message:     ErrorCode.Str(E132),
+			Solution:    SolutionMessage.Str(S127),
+			Code:        132,
+		}
+		return StashEntry{}, &encErr
+	}
+
+	stashDir, err := getStashDir()
+	if err != nil {
+		return StashEntry{}, err
+	}
+
+	now := time.Now().UTC()
+	entry := StashEntry{Env: config.CurrentEnv, Message: message, Created: now.Truncate(time.Second)}
+	entry.dir = filepath.Join(stashDir, now.Format("20060102T150405.000000000"))
+
+	if mkdirErr := os.MkdirAll(entry.dir, privateDirMode); mkdirErr != nil {
+		return StashEntry{}, stashError(mkdirErr)
+	}
+
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+
+		data, readErr := ioutil.ReadFile(filepath.Join(projectRoot, trackedFile))
+		if errors.Is(readErr, os.ErrNotExist) {
+			continue
+		} else if readErr != nil {
+			os.RemoveAll(entry.dir)
+			return StashEntry{}, stashError(readErr)
+		}
+
+		if writeErr := ioutil.WriteFile(filepath.Join(entry.dir, trackedFileName), unrenderConfigFile(trackedFileName, data), privateFileMode); writeErr != nil {
+			os.RemoveAll(entry.dir)
+			return StashEntry{}, stashError(writeErr)
+		}
+	}
+
+	data, ymlErr := yaml.Marshal(&entry)
+	if ymlErr == nil {
+		ymlErr = ioutil.WriteFile(filepath.Join(entry.dir, stashMetaFileName), data, privateFileMode)
+	}
+	if ymlErr != nil {
+		os.RemoveAll(entry.dir)
+		return StashEntry{}, stashError(ymlErr)
+	}
+	log.Debug(fmt.Sprintf("Stashed the working files to %s", entry.dir))
+
+	if _, err := getEnvDirPath(config.CurrentEnv); err == nil {
+		for _, trackedFile := range config.TrackedFiles() {
+			if _, err := setConfigAsMain(filepath.Join(projectRoot, trackedFile), config.CurrentEnv); err != nil {
+				return entry, err
+			}
+		}
+
+		if err := recordWorkingCopyBase(config, config.CurrentEnv, nil); err != nil {
+			log.Warn(fmt.Sprintf("%s: %s", err.Message, err.OriginalErr))
+		}
+	}
+
+	return entry, nil
+}
+
+// StashApply
+// Writes the files of a stash entry to the working configuration files. The
+// working files must not hold changes of their own. When drop is set, the
+// entry is removed after it was applied
+func StashApply(index int, drop bool) (StashEntry, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return StashEntry{}, err
+	}
+
+	projectRoot, err := resolveJorgeDir()
+	if err != nil {
+		return StashEntry{}, err
+	}
+
+	entry, err := getStashEntry(index)
+	if err != nil {
+		return StashEntry{}, err
+	}
+
+	if _, envErr := getEnvDirPath(config.CurrentEnv); envErr == nil && workingCopyChanged(config) {
+		encErr := EncapsulatedError{
+			OriginalErr: ErrorCode.Err(E134),
+			Message:     ErrorCode.Str(E134),
+			Solution:    SolutionMessage.Str(S128),
+			Code:        134,
+		}
+		return StashEntry{}, &encErr
+	}
+
+	if entry.Env != config.CurrentEnv {
+		log.Warn(fmt.Sprintf("stash@{%d} was made on %s, applying it on %s", index, entry.Env, config.CurrentEnv))
+	}
+
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+
+		data, readErr := ioutil.ReadFile(filepath.Join(entry.dir, trackedFileName))
+		if errors.Is(readErr, os.ErrNotExist) {
+			continue
+		} else if readErr != nil {
+			return StashEntry{}, stashError(readErr)
+		}
+
+		renderedData, secretRefs, err := renderConfigFile(config.CurrentEnv, trackedFileName, data)
+		if err != nil && err.Code == 111 {
+			renderedData, secretRefs, err = resolveSecrets(config.Secrets, data)
+		}
+		if err != nil {
+			return StashEntry{}, err
+		}
+
+		workingFilePath := filepath.Join(projectRoot, trackedFile)
+		writeErr := removeSymlink(workingFilePath)
+		if writeErr == nil {
+			writeErr = ioutil.WriteFile(workingFilePath, renderedData, privateFileMode)
+		}
+
+		if writeErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: writeErr,
+				Message:     ErrorCode.Str(E007),
+				Solution:    SolutionMessage.Str(S002, GetUser()),
+				Code:        7,
+			}
+			return StashEntry{}, &encErr
+		}
+
+		if err := applyFileAttributes(workingFilePath, config, config.CurrentEnv, trackedFileName); err != nil {
+			return StashEntry{}, err
+		}
+
+		if err := setSecretRefs(trackedFileName, secretRefs); err != nil {
+			return StashEntry{}, err
+		}
+	}
+
+	if drop {
+		if removeErr := os.RemoveAll(entry.dir); removeErr != nil {
+			return entry, stashError(removeErr)
+		}
+	}
+
+	return entry, nil
+}
+
+// StashDrop
+// Removes a stash entry without applying it
+func StashDrop(index int) (StashEntry, *EncapsulatedError) {
+	entry, err := getStashEntry(index)
+	if err != nil {
+		return StashEntry{}, err
+	}
+
+	if removeErr := os.RemoveAll(entry.dir); removeErr != nil {
+		return StashEntry{}, stashError(removeErr)
+	}
+
+	return entry, nil
+}
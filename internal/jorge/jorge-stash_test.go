@@ -0,0 +1,60 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStashPushAndPop(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "default"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.Remove(filepath.Join(testingRoot, ".env"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\nmode: symlink\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env"), []byte("HOST=localhost\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".env"), []byte("HOST=localhost\n"), 0600)
+
+	if _, err := StashPush(""); err == nil || err.Code != 132 {
+		t.Fatalf("Expected code %d, but found %v", 132, err)
+	}
+
+	os.WriteFile(filepath.Join(testingRoot, ".env"), []byte("HOST=half-done\n"), 0600)
+
+	if _, err := StashPush("try staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, ".env")); string(data) != "HOST=localhost\n" {
+		t.Fatalf("Working file was not restored: %s", data)
+	}
+
+	if entries, err := StashList(); err != nil || len(entries) != 1 || entries[0].Message != "try staging" || entries[0].Env != "default" {
+		t.Fatalf("Unexpected stash entries %v (%v)", entries, err)
+	}
+
+	if _, err := StashApply(1, true); err == nil || err.Code != 133 {
+		t.Fatalf("Expected code %d, but found %v", 133, err)
+	}
+
+	if _, err := StashApply(0, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, ".env")); string(data) != "HOST=half-done\n" {
+		t.Fatalf("Stash was not applied: %s", data)
+	}
+
+	if info, err := os.Lstat(filepath.Join(testingRoot, ".env")); err != nil || !info.Mode().IsRegular() || info.Mode().Perm() != privateFileMode {
+		t.Fatalf("Unexpected working file %v (%v)", info.Mode(), err)
+	}
+
+	if entries, _ := StashList(); len(entries) != 0 {
+		t.Fatalf("Stash entry was not dropped: %v", entries)
+	}
+}
@@ -0,0 +1,104 @@
+package jorge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Ways of writing the working configuration files. In symlink mode the
+// working files are links to the stored files of the current environment, so
+// that edits land directly in the environment
+const (
+	ModeCopy    = "copy"
+	ModeSymlink = "symlink"
+)
+
+// symlinkModeEnabled
+// Determines whether the project links the working files instead of copying
+// them. Projects whose config can not be read copy them
+func symlinkModeEnabled() bool {
+	config, err := getInternalConfig()
+	if err != nil {
+		return false
+	}
+
+	switch config.Mode {
+	case "", ModeCopy:
+		return false
+	case ModeSymlink:
+		return true
+	default:
+		log.Warn(fmt.Sprintf("Unknown mode %s in config.yml, copying the configuration files", config.Mode))
+		return false
+	}
+}
+
+// isSymlink
+// Determines whether the path is a symbolic link
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// removeSymlink
+// Removes the path when it is a symbolic link, so that writing to it does not
+// change the file it points to
+func removeSymlink(path string) error {
+	if !isSymlink(path) {
+		return nil
+	}
+
+	log.Debug(fmt.Sprintf("Removing the link %s", path))
+	return os.Remove(path)
+}
+
+// isLinkedTo
+// Determines whether the path is, or points to, the given file
+func isLinkedTo(path string, file string) bool {
+	pathInfo, pathErr := os.Stat(path)
+	fileInfo, fileErr := os.Stat(file)
+
+	return pathErr == nil && fileErr == nil && os.SameFile(pathInfo, fileInfo)
+}
+
+// linkConfigFile
+// Replaces the working file at target with a relative link to a stored file.
+// The link is created next to the target and renamed over it, so the working
+// file is never missing
+func linkConfigFile(target string, storedFilePath string) error {
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+
+	absStoredFilePath, err := filepath.Abs(storedFilePath)
+	if err != nil {
+		return err
+	}
+
+	linkPath, err := filepath.Rel(filepath.Dir(absTarget), absStoredFilePath)
+	if err != nil {
+		return err
+	}
+
+	tempLink := absTarget + ".jorge-link"
+	if removeErr := os.Remove(tempLink); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+		return removeErr
+	}
+
+	if err := os.Symlink(linkPath, tempLink); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tempLink, absTarget); err != nil {
+		os.Remove(tempLink)
+		return err
+	}
+
+	log.Debug(fmt.Sprintf("Linked %s to %s", target, linkPath))
+	return nil
+}
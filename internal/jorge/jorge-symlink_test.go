@@ -0,0 +1,75 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSymlinkMode(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "default"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "staging"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.Remove(filepath.Join(testingRoot, ".env"))
+	defer os.Remove(filepath.Join(testingRoot, ".gitignore"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: staging\nconfigFilePath: .env\nmode: symlink\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env"), []byte("HOST=localhost\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env"), []byte("HOST=staging\n"), 0600)
+
+	if _, err := UseConfigFile("default", false); err != nil {
+		t.Fatal(err)
+	}
+
+	workingFilePath := filepath.Join(testingRoot, ".env")
+	defaultFilePath := filepath.Join(testingRoot, ".jorge", "envs", "default", ".env")
+	if !isSymlink(workingFilePath) || !isLinkedTo(workingFilePath, defaultFilePath) {
+		t.Fatalf("%s is not linked to %s", workingFilePath, defaultFilePath)
+	}
+
+	// Edits land directly in the environment and committing keeps them
+	os.WriteFile(workingFilePath, []byte("HOST=127.0.0.1\n"), 0600)
+
+	if err := CommitCurrentEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(defaultFilePath); string(data) != "HOST=127.0.0.1\n" {
+		t.Fatalf("Unexpected stored file %s", data)
+	}
+
+	if _, err := UseConfigFile("staging", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(workingFilePath); string(data) != "HOST=staging\n" {
+		t.Fatalf("Unexpected working file %s", data)
+	}
+
+	if data, _ := os.ReadFile(defaultFilePath); string(data) != "HOST=127.0.0.1\n" {
+		t.Fatalf("Switching environments changed %s: %s", defaultFilePath, data)
+	}
+
+	// A link that points to another environment is reported and repaired
+	os.Remove(workingFilePath)
+	os.Symlink(defaultFilePath, workingFilePath)
+
+	findings, err := Doctor(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repaired := false
+	for _, finding := range findings {
+		repaired = repaired || (finding.Check == "symlink" && finding.Fixed)
+	}
+
+	if !repaired || !isLinkedTo(workingFilePath, filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env")) {
+		t.Fatalf("Link to another environment was not repaired: %v", findings)
+	}
+}
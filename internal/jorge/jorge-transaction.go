@@ -0,0 +1,452 @@
+package jorge
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const journalDirName = "journal"
+const journalFileName = "journal.yml"
+
+// pendingJournalPrefix marks the journals that are still being created. They
+// are ignored by the other processes until they are renamed
+const pendingJournalPrefix = ".pending-"
+const pendingJournalTimeout = 10 * time.Minute
+
+// journalEntry
+// A path that an operation changes, with the copy of what it held before
+type journalEntry struct {
+	Path    string `yaml:"path"`
+	Existed bool   `yaml:"existed"`
+	Link    string `yaml:"link,omitempty"`
+	Backup  string `yaml:"backup,omitempty"`
+}
+
+// journal
+// The record of an operation in progress. It is kept in the store until the
+// operation finishes, so that an operation that was interrupted can be
+// rolled back
+type journal struct {
+	Operation string         `yaml:"operation"`
+	Pid       int            `yaml:"pid"`
+	Started   time.Time      `yaml:"started"`
+	Entries   []journalEntry `yaml:"entries"`
+}
+
+// transaction
+// Groups the changes of an operation that touches several files, so that
+// they are either all kept or all undone. The paths are tracked before they
+// are changed. Only the paths in the store and the paths that are allowed
+// explicitly, e.g. the working files, can be tracked
+type transaction struct {
+	dir     string
+	store   string
+	allowed []string
+	journal journal
+	done    bool
+}
+
+// UnfinishedOperation
+// An operation that was interrupted before it finished
+type UnfinishedOperation struct {
+	Operation string    `json:"operation" yaml:"operation"`
+	Started   time.Time `json:"started" yaml:"started"`
+	Journal   string    `json:"journal" yaml:"journal"`
+}
+
+// journalError
+// Wraps an error that occurred while recording the changes of an operation
+func journalError(err error, path string) *EncapsulatedError {
+	encErr := EncapsulatedError{
+		OriginalErr: err,
+		Message:     ErrorCode.Str(E151),
+		Solution:    SolutionMessage.Str(S136, GetUser(), path),
+		Code:        151,
+	}
+	return &encErr
+}
+
+// beginTransaction
+// Starts recording the changes of an operation in the journal of the store.
+// The unfinished operations of processes that no longer run are rolled back
+// first. The journal is written before its directory gets its name, so that
+// other processes never find a journal without the process it belongs to
+func beginTransaction(jorgeDir string, operation string) (*transaction, *EncapsulatedError) {
+	for _, unfinished := range findUnfinishedOperations(jorgeDir) {
+		if err := rollbackJournal(unfinished.Journal); err != nil {
+			log.Warn(fmt.Sprintf("Could not roll back the unfinished %s operation of %s: %v", unfinished.Operation, unfinished.Started.Local().Format("2006-01-02 15:04"), err))
+		} else {
+			log.Warn(fmt.Sprintf("Rolled back the unfinished %s operation of %s", unfinished.Operation, unfinished.Started.Local().Format("2006-01-02 15:04")))
+		}
+	}
+
+	store, absErr := filepath.Abs(jorgeDir)
+	if absErr != nil {
+		return nil, journalError(absErr, jorgeDir)
+	}
+
+	journalsDir := filepath.Join(store, journalDirName)
+	removeStalePendingJournals(journalsDir)
+
+	now := time.Now().UTC()
+	dir := filepath.Join(journalsDir, now.Format("20060102T150405.000000000")+"-"+operation)
+	tx := transaction{
+		store:   store,
+		allowed: []string{},
+		journal: journal{Operation: operation, Pid: os.Getpid(), Started: now, Entries: []journalEntry{}},
+	}
+
+	// Another process may remove the journal directory when its operation
+	// finishes, right after it was created
+	var pendingDir string
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if err = os.MkdirAll(journalsDir, privateDirMode); err == nil {
+			if pendingDir, err = ioutil.TempDir(journalsDir, pendingJournalPrefix); !errors.Is(err, os.ErrNotExist) {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, journalError(err, journalsDir)
+	}
+
+	tx.dir = pendingDir
+	if err := tx.save(); err != nil {
+		os.RemoveAll(pendingDir)
+		return nil, err
+	}
+
+	if err := os.Rename(pendingDir, dir); err != nil {
+		os.RemoveAll(pendingDir)
+		return nil, journalError(err, dir)
+	}
+	tx.dir = dir
+
+	log.Debug(fmt.Sprintf("Started the %s operation at %s", operation, tx.dir))
+	return &tx, nil
+}
+
+// save
+// Writes the journal next to the copies it refers to. It is written to a
+// temporary file first, so that an interrupted write leaves the previous one
+func (tx *transaction) save() *EncapsulatedError {
+	journalPath := filepath.Join(tx.dir, journalFileName)
+	data, err := yaml.Marshal(&tx.journal)
+	if err == nil {
+		err = ioutil.WriteFile(journalPath+".tmp", data, privateFileMode)
+	}
+	if err == nil {
+		err = os.Rename(journalPath+".tmp", journalPath)
+	}
+
+	if err != nil {
+		return journalError(err, journalPath)
+	}
+	return nil
+}
+
+// isWithinPath
+// Determines whether the path is the parent directory or one of its
+// descendants
+func isWithinPath(parent string, path string) bool {
+	relPath, err := filepath.Rel(parent, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// allow
+// Lets the transaction track paths outside of the store
+func (tx *transaction) allow(paths ...string) {
+	for _, path := range paths {
+		if absPath, err := filepath.Abs(path); err == nil {
+			tx.allowed = append(tx.allowed, absPath)
+		}
+	}
+}
+
+// canTrack
+// Refuses the paths that hold the journal, since a rollback would remove the
+// copies it restores, and the paths outside of the store that were not
+// allowed
+func (tx *transaction) canTrack(path string) *EncapsulatedError {
+	if isWithinPath(path, tx.dir) || (!isWithinPath(tx.store, path) && !Contains(tx.allowed, path)) {
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("refusing to record %s in the journal %s", path, tx.dir),
+			Message:     ErrorCode.Str(E151),
+			Solution:    SolutionMessage.Str(S144, path),
+			Code:        151,
+		}
+		return &encErr
+	}
+
+	return nil
+}
+
+// isTracked
+// Determines whether the path is already tracked
+func (tx *transaction) isTracked(path string) bool {
+	for _, entry := range tx.journal.Entries {
+		if entry.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// track
+// Records the current state of a file or directory before the operation
+// changes it. Paths that do not exist are removed by a rollback
+func (tx *transaction) track(path string) *EncapsulatedError {
+	path, absErr := filepath.Abs(path)
+	if absErr != nil {
+		return journalError(absErr, path)
+	}
+
+	if tx.isTracked(path) {
+		return nil
+	}
+
+	if err := tx.canTrack(path); err != nil {
+		return err
+	}
+
+	entry := journalEntry{Path: path}
+	info, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		err = nil
+	case err != nil:
+	case info.Mode()&os.ModeSymlink != 0:
+		entry.Existed = true
+		entry.Link, err = os.Readlink(path)
+	case info.IsDir():
+		entry.Existed, entry.Backup = true, strconv.Itoa(len(tx.journal.Entries))
+		err = copyDir(path, filepath.Join(tx.dir, entry.Backup), []string{tx.dir})
+	default:
+		entry.Existed, entry.Backup = true, strconv.Itoa(len(tx.journal.Entries))
+		err = copyFile(path, filepath.Join(tx.dir, entry.Backup), info.Mode().Perm())
+	}
+
+	if err != nil {
+		return journalError(err, path)
+	}
+
+	tx.journal.Entries = append(tx.journal.Entries, entry)
+	return tx.save()
+}
+
+// created
+// Records a path that the operation created, so that a rollback removes it.
+// It is the only way to record the store itself, which holds the journal
+func (tx *transaction) created(path string) *EncapsulatedError {
+	path, absErr := filepath.Abs(path)
+	if absErr != nil {
+		return journalError(absErr, path)
+	}
+
+	if tx.isTracked(path) {
+		return nil
+	}
+
+	if path != tx.store {
+		if err := tx.canTrack(path); err != nil {
+			return err
+		}
+	}
+
+	tx.journal.Entries = append(tx.journal.Entries, journalEntry{Path: path})
+	return tx.save()
+}
+
+// trackAll
+// Tracks every path, stopping at the first one that can not be recorded
+func (tx *transaction) trackAll(paths []string) *EncapsulatedError {
+	for _, path := range paths {
+		if err := tx.track(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackEnvSwitch
+// Tracks the paths of a project that change when it switches from an
+// environment to another: the working files, the internal configuration, the
+// secret references and the bases of the working copy of both environments
+func (tx *transaction) trackEnvSwitch(config JorgeConfig, location ProjectLocation, fromEnv string, toEnv string) *EncapsulatedError {
+	for _, envName := range []string{fromEnv, toEnv} {
+		if err := validateEnvName(envName); err != nil {
+			return err
+		}
+	}
+
+	envsDir := filepath.Join(location.Store, "envs")
+	paths := []string{
+		filepath.Join(location.Store, configFileName),
+		filepath.Join(location.Store, secretRefsFileName),
+		filepath.Join(envsDir, fromEnv, baseDirName),
+		filepath.Join(envsDir, toEnv),
+	}
+
+	for _, trackedFile := range config.TrackedFiles() {
+		workingFile := filepath.Join(location.Root, trackedFile)
+		tx.allow(workingFile)
+		paths = append(paths, workingFile)
+	}
+
+	return tx.trackAll(paths)
+}
+
+// commit
+// Keeps the changes of the operation and forgets the journal
+func (tx *transaction) commit() {
+	tx.done = true
+	removeJournal(tx.dir)
+	log.Debug(fmt.Sprintf("Finished the %s operation", tx.journal.Operation))
+}
+
+// close
+// Rolls back the changes of an operation that was not committed. It is meant
+// to be deferred right after the transaction begins
+func (tx *transaction) close() {
+	if tx.done {
+		return
+	}
+	tx.done = true
+
+	if err := rollbackJournal(tx.dir); err != nil {
+		log.Warn(fmt.Sprintf("Could not undo the changes of the %s operation: %v. Run `jorge doctor --fix` to retry", tx.journal.Operation, err))
+	} else {
+		log.Debug(fmt.Sprintf("Rolled back the %s operation", tx.journal.Operation))
+	}
+}
+
+// removeJournal
+// Removes the journal of an operation, and the journal directory of the
+// store when no other operation is in progress
+func removeJournal(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		log.Warn(fmt.Sprintf("Could not remove the journal %s: %v", dir, err))
+	}
+	os.Remove(filepath.Dir(dir))
+}
+
+// rollbackJournal
+// Restores the paths that the journal tracked, the last one first, and
+// removes the journal. A journal that could not be rolled back completely is
+// kept, so that the rollback can be retried
+func rollbackJournal(dir string) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, journalFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		// The operation was interrupted before it changed anything
+		removeJournal(dir)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var recorded journal
+	if err := yaml.Unmarshal(data, &recorded); err != nil {
+		return err
+	}
+
+	var rollbackErr error
+	for i := len(recorded.Entries) - 1; i >= 0; i-- {
+		entry := recorded.Entries[i]
+
+		// The copies of the journal are never removed before they are restored
+		if entry.Existed && isWithinPath(entry.Path, dir) {
+			log.Warn(fmt.Sprintf("Not restoring %s, it holds the journal %s", entry.Path, dir))
+			continue
+		}
+
+		err := os.RemoveAll(entry.Path)
+		if err == nil && entry.Existed {
+			if len(entry.Link) > 0 {
+				err = os.Symlink(entry.Link, entry.Path)
+			} else {
+				backup := filepath.Join(dir, entry.Backup)
+				if info, statErr := os.Stat(backup); statErr != nil {
+					err = statErr
+				} else if info.IsDir() {
+					err = copyDir(backup, entry.Path, []string{})
+				} else if err = copyFile(backup, entry.Path, info.Mode().Perm()); err == nil {
+					err = os.Chmod(entry.Path, info.Mode().Perm())
+				}
+			}
+		}
+
+		if err != nil {
+			log.Warn(fmt.Sprintf("Could not restore %s: %v", entry.Path, err))
+			rollbackErr = err
+		}
+	}
+
+	if rollbackErr != nil {
+		return rollbackErr
+	}
+
+	// The journal may have been removed with a directory that the operation
+	// created
+	removeJournal(dir)
+	return nil
+}
+
+// removeStalePendingJournals
+// Removes the journals that were left while they were being created, e.g. by
+// a crash. They recorded no changes, so there is nothing to roll back
+func removeStalePendingJournals(journalsDir string) {
+	entries, err := ioutil.ReadDir(journalsDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), pendingJournalPrefix) && time.Since(entry.ModTime()) > pendingJournalTimeout {
+			os.RemoveAll(filepath.Join(journalsDir, entry.Name()))
+		}
+	}
+}
+
+// findUnfinishedOperations
+// Returns the operations of the store whose process no longer runs
+func findUnfinishedOperations(jorgeDir string) []UnfinishedOperation {
+	journalsDir := filepath.Join(jorgeDir, journalDirName)
+	entries, err := ioutil.ReadDir(journalsDir)
+	if err != nil {
+		return []UnfinishedOperation{}
+	}
+
+	unfinished := []UnfinishedOperation{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), pendingJournalPrefix) {
+			continue
+		}
+
+		dir := filepath.Join(journalsDir, entry.Name())
+
+		var recorded journal
+		if data, readErr := ioutil.ReadFile(filepath.Join(dir, journalFileName)); readErr == nil {
+			if yaml.Unmarshal(data, &recorded) != nil {
+				continue
+			}
+		}
+
+		if recorded.Pid > 0 && processAlive(recorded.Pid) {
+			continue
+		}
+
+		unfinished = append(unfinished, UnfinishedOperation{Operation: recorded.Operation, Started: recorded.Started, Journal: dir})
+	}
+
+	return unfinished
+}
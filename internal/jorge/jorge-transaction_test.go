@@ -0,0 +1,181 @@
+package jorge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFailedUseIsRolledBack(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "default"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "staging"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+	defer os.Remove(filepath.Join(testingRoot, ".env"))
+
+	// The second tracked file can not be written, since a directory is in
+	// its place
+	os.Mkdir(filepath.Join(testingRoot, "settings"), 0700)
+	defer os.Remove(filepath.Join(testingRoot, "settings"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\nextraConfigFiles:\n- settings\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", ".env"), []byte("HOST=localhost\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "default", "settings"), []byte("debug\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", ".env"), []byte("HOST=staging\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "staging", "settings"), []byte("release\n"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".env"), []byte("HOST=localhost\n"), 0600)
+
+	if _, err := UseConfigFile("staging", false); err == nil {
+		t.Fatal("Expected the use to fail")
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(testingRoot, ".env")); string(data) != "HOST=localhost\n" {
+		t.Fatalf("The working file was not rolled back: %s", data)
+	}
+
+	if config, err := getInternalConfig(); err != nil {
+		t.Fatal(err)
+	} else if config.CurrentEnv != "default" {
+		t.Fatalf("Unexpected current environment %s", config.CurrentEnv)
+	}
+
+	if _, err := os.Stat(filepath.Join(testingRoot, ".jorge", journalDirName)); !os.IsNotExist(err) {
+		t.Fatal("The journal was not removed")
+	}
+}
+
+func TestUnfinishedOperationIsRolledBack(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	jorgeDir := filepath.Join(testingRoot, ".jorge")
+	os.MkdirAll(filepath.Join(jorgeDir, "envs", "default"), 0700)
+	defer os.RemoveAll(jorgeDir)
+
+	os.WriteFile(filepath.Join(jorgeDir, "config.yml"), []byte("version: 1\ncurrentEnv: default\n"), 0600)
+
+	tx, err := beginTransaction(jorgeDir, "use")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(jorgeDir, "config.yml")
+	createdPath := filepath.Join(jorgeDir, "envs", "staging")
+	if err := tx.trackAll([]string{configPath, createdPath}); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(configPath, []byte("version: 1\ncurrentEnv: staging\n"), 0600)
+	os.Mkdir(createdPath, 0700)
+
+	// The process that started the operation is gone
+	tx.journal.Pid = 0
+	if err := tx.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	unfinished := findUnfinishedOperations(jorgeDir)
+	if len(unfinished) != 1 || unfinished[0].Operation != "use" {
+		t.Fatalf("Unexpected unfinished operations %v", unfinished)
+	}
+
+	// The next operation rolls it back first
+	next, err := beginTransaction(jorgeDir, "commit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.commit()
+
+	if data, _ := os.ReadFile(configPath); !strings.Contains(string(data), "currentEnv: default") {
+		t.Fatalf("The configuration was not rolled back: %s", data)
+	}
+
+	if _, err := os.Stat(createdPath); !os.IsNotExist(err) {
+		t.Fatal("The created environment was not removed")
+	}
+
+	if _, err := os.Stat(filepath.Join(jorgeDir, journalDirName)); !os.IsNotExist(err) {
+		t.Fatal("The journal was not removed")
+	}
+}
+
+func TestTransactionKeepsTheStore(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	jorgeDir := filepath.Join(testingRoot, ".jorge")
+	os.MkdirAll(filepath.Join(jorgeDir, "envs", "default"), 0700)
+	defer os.RemoveAll(jorgeDir)
+	defer os.Remove(filepath.Join(testingRoot, ".env"))
+
+	os.WriteFile(filepath.Join(jorgeDir, "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env\n"), 0600)
+	os.WriteFile(filepath.Join(jorgeDir, "envs", "default", ".env"), []byte("HOST=localhost\n"), 0600)
+
+	// Names that point outside of the envs directory are refused before
+	// anything is recorded
+	for _, envName := range []string{"..", ".", "", "../envs", "a/b"} {
+		for _, createEnv := range []bool{false, true} {
+			if _, err := UseConfigFile(envName, createEnv); err == nil || err.Code != 118 {
+				t.Fatalf("Expected %q to be refused, got %v", envName, err)
+			}
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(jorgeDir, "envs", "default", ".env")); err != nil || string(data) != "HOST=localhost\n" {
+		t.Fatal("The store was changed")
+	}
+
+	tx, err := beginTransaction(jorgeDir, "use")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.close()
+
+	for _, path := range []string{jorgeDir, filepath.Join(jorgeDir, journalDirName), testingRoot, filepath.Join(testingRoot, ".env")} {
+		if err := tx.track(path); err == nil {
+			t.Fatalf("Expected %s not to be tracked", path)
+		}
+	}
+
+	tx.allow(filepath.Join(testingRoot, ".env"))
+	if err := tx.track(filepath.Join(testingRoot, ".env")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPendingJournalIsNotRolledBack(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	jorgeDir := filepath.Join(testingRoot, ".jorge")
+	os.MkdirAll(filepath.Join(jorgeDir, "envs", "default"), 0700)
+	defer os.RemoveAll(jorgeDir)
+
+	// Another process is creating its journal
+	pendingDir := filepath.Join(jorgeDir, journalDirName, pendingJournalPrefix+"other")
+	os.MkdirAll(pendingDir, 0700)
+
+	if unfinished := findUnfinishedOperations(jorgeDir); len(unfinished) != 0 {
+		t.Fatalf("Unexpected unfinished operations %v", unfinished)
+	}
+
+	tx, err := beginTransaction(jorgeDir, "use")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.commit()
+
+	if _, err := os.Stat(pendingDir); err != nil {
+		t.Fatalf("The journal of the other process was removed (%v)", err)
+	}
+}
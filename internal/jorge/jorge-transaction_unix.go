@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package jorge
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive
+// Determines whether a process with the pid runs
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package jorge
+
+import "os"
+
+// processAlive
+// Determines whether a process with the pid runs. Opening a process fails on
+// windows when it does not exist
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	process.Release()
+	return true
+}
@@ -1,13 +1,15 @@
 package jorge
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -17,8 +19,25 @@ const jorgeConfigDir = ".jorge"
 const configFileName = "config.yml"
 
 type JorgeConfig struct {
-	CurrentEnv     string `yaml:"currentEnv"`
-	ConfigFilePath string `yaml:"configFilePath"`
+	CurrentEnv       string                       `yaml:"currentEnv"`
+	ConfigFilePath   string                       `yaml:"configFilePath"`
+	ExtraConfigFiles []string                     `yaml:"extraConfigFiles,omitempty"`
+	Version          int                          `yaml:"version"`
+	Hooks            JorgeHooks                   `yaml:"hooks,omitempty"`
+	Secrets          JorgeSecrets                 `yaml:"secrets,omitempty"`
+	Snapshots        JorgeSnapshots               `yaml:"snapshots,omitempty"`
+	Mode             string                       `yaml:"mode,omitempty"`
+	FileMode         string                       `yaml:"fileMode,omitempty"`
+	Projects         []string                     `yaml:"projects,omitempty"`
+	Profiles         map[string]map[string]string `yaml:"profiles,omitempty"`
+	Root             string                       `yaml:"root,omitempty"`
+}
+
+// TrackedFiles
+// Returns the paths, relative to the project root, of every configuration file
+// that is managed by jorge. The first one is the main configuration file
+func (c JorgeConfig) TrackedFiles() []string {
+	return append([]string{c.ConfigFilePath}, c.ExtraConfigFiles...)
 }
 
 // hasJorgeDir
@@ -34,54 +53,22 @@ func hasJorgeDir(path string) bool {
 // resolveJorgeDir
 // The jorge command uses the current active directory as it's root. This function
 // determines if the current directory has the .jorge dir in it, or if this
-// directory belongs to a jorge project by searching the .jorge dir in it's
-// ancestors
+// directory belongs to a jorge project by searching the .jorge dir (or a
+// .jorgerc file) in it's ancestors. See locateProject
 func resolveJorgeDir() (string, *EncapsulatedError) {
-	currentAbsPath, err := filepath.Abs(filepath.Clean(filepath.Join(".")))
-
-	if err != nil {
-		encErr := EncapsulatedError{
-			OriginalErr: err,
-			Message:     ErrorCode.Str(E000),
-			Solution:    SolutionMessage.Str(S000),
-			Code:        1,
-		}
-		return "", &encErr
-	}
-
-	parts := strings.Split(currentAbsPath, string(os.PathSeparator))
-	var currentActivePath string
-	currentActivePath = currentAbsPath
-
-	for i := 0; i < len(parts); i++ {
-		if hasJorgeDir(currentActivePath) {
-			log.Debug("Resolve jorge dir at ", currentActivePath)
-			return currentActivePath, nil
-		} else {
-			currentActivePath = filepath.Join(currentActivePath, "..")
-		}
-	}
-
-	encErr := EncapsulatedError{
-		OriginalErr: ErrorCode.Err(E100),
-		Message:     ErrorCode.Str(E100),
-		Solution:    SolutionMessage.Str(S100),
-		Code:        100,
-	}
-
-	return "", &encErr
+	location, err := locateProject()
+	return location.Root, err
 }
 
 // getJorgeDir
 // Resolves the path of the .jorge directory and returns an absolute path that
 // leads to it
 func getJorgeDir() (string, *EncapsulatedError) {
-	basePath, err := resolveJorgeDir()
-	jorgeDir := filepath.Join(basePath, ".jorge")
-
+	location, err := locateProject()
 	if err != nil {
 		return "", err
 	}
+	jorgeDir := location.Store
 
 	if configDir, err := os.Stat(jorgeDir); err == nil {
 
@@ -110,44 +97,45 @@ func getJorgeDir() (string, *EncapsulatedError) {
 }
 
 // createJorgeDir
-// Creates the .jorge directory in the current active directory of the process
+// Creates the .jorge directory in the current active directory of the process,
+// or the store that $JORGE_DIR or the .jorgerc file points to
 func createJorgeDir() (string, *EncapsulatedError) {
 
-	configDirPath := filepath.Join(jorgeConfigDir)
+	if _, err := getJorgeDir(); err == nil {
+		return "", err
+	} else if !ErrorCode(E100).Is(ErrorCode(err.Message)) {
+		return "", err
+	}
 
-	if _, err := resolveJorgeDir(); err == nil {
+	// The store of a project that was found, e.g. by its .jorgerc file, is
+	// created where it is expected
+	location, err := locateProject()
+	if err != nil && !ErrorCode(E100).Is(ErrorCode(err.Message)) {
 		return "", err
-	} else if ErrorCode(E100).Is(ErrorCode(err.Message)) {
-		if err := os.Mkdir(configDirPath, 0700); err != nil {
-			encErr := EncapsulatedError{
-				OriginalErr: err,
-				Message:     ErrorCode(E003).Str(),
-				Solution:    SolutionMessage.Str(S002, GetUser()),
-				Code:        3,
-			}
-			return "", &encErr
+	} else if err != nil {
+		basePath, absErr := filepath.Abs(".")
+		if absErr != nil {
+			return "", locationError(absErr)
 		}
 
-		if basePath, err := resolveJorgeDir(); err == nil {
-			log.Debug("Created .jorge dir")
-			return filepath.Join(basePath, configDirPath), nil
-		} else {
+		if location.Store, _, err = getStoreLocation(basePath); err != nil {
 			return "", err
 		}
-	} else {
-		return "", err
 	}
-}
+	configDirPath := location.Store
 
-// removeJorgeDir
-// Deletes the .jorge directory and all of it's contents
-func removeJorgeDir() *EncapsulatedError {
-	if jorgeDir, err := resolveJorgeDir(); err != nil {
-		return err
-	} else {
-		fmt.Printf("Jorge init failed. Please remove %s\n", filepath.Join(jorgeDir, ".jorge"))
-		return nil
+	if err := os.MkdirAll(configDirPath, 0700); err != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: err,
+			Message:     ErrorCode(E003).Str(),
+			Solution:    SolutionMessage.Str(S002, GetUser()),
+			Code:        3,
+		}
+		return "", &encErr
 	}
+
+	log.Debug("Created .jorge dir")
+	return configDirPath, nil
 }
 
 // createJorgeEnvsDir
@@ -247,8 +235,8 @@ func getInternalConfig() (JorgeConfig, *EncapsulatedError) {
 
 	fileData, err := ioutil.ReadFile(configFilePath)
 
-	data := make(map[string]string)
-	ymlError := yaml.Unmarshal(fileData, &data)
+	var config JorgeConfig
+	ymlError := yaml.Unmarshal(fileData, &config)
 
 	if ymlError != nil {
 		encErr := EncapsulatedError{
@@ -260,9 +248,12 @@ func getInternalConfig() (JorgeConfig, *EncapsulatedError) {
 		return JorgeConfig{}, &encErr
 	}
 
-	var config = JorgeConfig{
-		CurrentEnv:     data["currentEnv"],
-		ConfigFilePath: data["configFilePath"],
+	if config.Version != storeVersion {
+		if err := migrateStore(jorgeDir, config.Version); err != nil {
+			return JorgeConfig{}, err
+		}
+
+		return getInternalConfig()
 	}
 
 	return config, nil
@@ -289,6 +280,7 @@ func setInternalConfig(configUpdates JorgeConfig) (JorgeConfig, *EncapsulatedErr
 	}
 
 	newConfig := currentConfig
+	newConfig.Version = storeVersion
 
 	numUpdates := 0
 	if currentConfig.CurrentEnv != configUpdates.CurrentEnv {
@@ -303,6 +295,18 @@ func setInternalConfig(configUpdates JorgeConfig) (JorgeConfig, *EncapsulatedErr
 		numUpdates++
 	}
 
+	if len(configUpdates.ExtraConfigFiles) > 0 {
+		newConfig.ExtraConfigFiles = configUpdates.ExtraConfigFiles
+		log.Debug(fmt.Sprintf("Found updated config key 'ExtraConfigFiles' (to '%v')", configUpdates.ExtraConfigFiles))
+		numUpdates++
+	}
+
+	if len(configUpdates.Root) > 0 && currentConfig.Root != configUpdates.Root {
+		newConfig.Root = configUpdates.Root
+		log.Debug(fmt.Sprintf("Found updated config key 'Root' (from '%s' to '%s')", currentConfig.Root, configUpdates.Root))
+		numUpdates++
+	}
+
 	if numUpdates == 0 {
 		log.Debug("Called setConfig without updates")
 	}
@@ -314,7 +318,7 @@ func setInternalConfig(configUpdates JorgeConfig) (JorgeConfig, *EncapsulatedErr
 		return JorgeConfig{}, err
 	}
 
-	if writeError := ioutil.WriteFile(configFilePath, data, 0700); writeError != nil {
+	if writeError := ioutil.WriteFile(configFilePath, data, privateFileMode); writeError != nil {
 		log.Debug(fmt.Sprintf("Error while writing file"))
 
 		encError := EncapsulatedError{
@@ -421,9 +425,47 @@ func setConfigAsMain(target string, envName string) (int64, *EncapsulatedError)
 
 	log.Debug(fmt.Sprintf("Source file %v found", sourceFilePath))
 
+	storedData, readErr := ioutil.ReadAll(sourceFile)
+	if readErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: readErr,
+			Message:     ErrorCode.Str(E006),
+			Solution:    SolutionMessage.Str(S003, sourceFilePath),
+			Code:        6,
+		}
+		return -1, &encErr
+	}
+
 	_, fileName := filepath.Split(target)
 
-	destination, destinationErr := os.Create(filepath.Join(target))
+	renderedData, secretRefs, err := renderConfigFile(envName, fileName, storedData)
+	if err != nil {
+		return -1, err
+	}
+
+	// A file that is rendered differently than it is stored can not be linked
+	if bytes.Equal(renderedData, storedData) && symlinkModeEnabled() {
+		if linkErr := linkConfigFile(target, sourceFilePath); linkErr == nil {
+			return int64(len(storedData)), setSecretRefs(fileName, secretRefs)
+		} else {
+			log.Warn(fmt.Sprintf("Could not link %s, copying it instead: %v", target, linkErr))
+		}
+	}
+
+	// Writing through a link would change the file it points to, which may
+	// be the stored file of another environment
+	if removeErr := removeSymlink(target); removeErr != nil {
+		encErr := EncapsulatedError{
+			OriginalErr: removeErr,
+			Message:     ErrorCode.Str(E007),
+			Solution:    SolutionMessage.Str(S002, GetUser()),
+			Code:        7,
+		}
+		return -1, &encErr
+	}
+
+	// Files that did not exist are private until a mode is recorded for them
+	destination, destinationErr := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, privateFileMode)
 	log.Debug(fmt.Sprintf("Target file path %v", target))
 	if destinationErr != nil {
 		encErr := EncapsulatedError{
@@ -436,9 +478,20 @@ func setConfigAsMain(target string, envName string) (int64, *EncapsulatedError)
 	}
 	defer destination.Close()
 
-	nBytes, copyErr := io.Copy(destination, sourceFile)
+	written, copyErr := destination.Write(renderedData)
+	nBytes := int64(written)
 	log.Debug(fmt.Sprintf("Wrote %d bytes to %v", nBytes, fileName))
 
+	if closeErr := destination.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+
+	if copyErr == nil {
+		if err := setSecretRefs(fileName, secretRefs); err != nil {
+			return -1, err
+		}
+	}
+
 	if copyErr != nil {
 		encError := EncapsulatedError{
 			OriginalErr: copyErr,
@@ -448,127 +501,231 @@ func setConfigAsMain(target string, envName string) (int64, *EncapsulatedError)
 		}
 		return -1, &encError
 	}
+
+	// Projects that can not be read keep the mode of the file
+	if config, err := getInternalConfig(); err == nil {
+		if err := applyFileAttributes(target, config, envName, fileName); err != nil {
+			return -1, err
+		}
+	}
+
 	return nBytes, nil
 }
 
-// requestConfigFileFromUser
-// It shows a cli prompt that accepts a string. The string is expected to be the
-// filepath to the user's configuration file
-func requestConfigFileFromUser() (string, *EncapsulatedError) {
-	fmt.Print("Config file path: ")
-	var filePath string
+// renderConfigFile
+// Creates the contents of a configuration file from the stored version of an
+// environment. The included shared environments are prepended and the secret
+// references are replaced with their values. The lines that held references
+// are returned indexed by the hash of the rendered line
+func renderConfigFile(envName string, fileName string, data []byte) ([]byte, map[string]string, *EncapsulatedError) {
+	renderedData, err := resolveIncludes(envName, fileName, data)
+	if err != nil {
+		return data, map[string]string{}, err
+	}
 
-	fmt.Scanln(&filePath)
+	if !secretRefPattern.Match(renderedData) {
+		return renderedData, map[string]string{}, nil
+	}
 
-	configFileRelativePath := filepath.Clean(filePath)
+	config, err := getInternalConfig()
+	if err != nil {
+		return data, map[string]string{}, err
+	}
 
-	if _, err := os.Stat(configFileRelativePath); err != nil {
-		if errors.Is(err, os.ErrNotExist) {
+	return resolveSecrets(config.Secrets, renderedData)
+}
+
+// unrenderConfigFile
+// Reverts the changes of renderConfigFile on a working configuration file, so
+// that neither the included keys nor the values of secrets are stored
+func unrenderConfigFile(fileName string, data []byte) []byte {
+	return restoreSecretRefs(fileName, stripIncludes(data))
+}
+
+func initializeJorgeProject(configFiles []string, interactive bool) ([]string, *EncapsulatedError) {
+	if len(configFiles) == 0 {
+		if !interactive {
 			encErr := EncapsulatedError{
-				OriginalErr: err,
-				Message:     ErrorCode.Str(E006),
-				Solution:    SolutionMessage.Str(S003, configFileRelativePath),
-				Code:        6,
+				OriginalErr: ErrorCode.Err(E121),
+				Message:     ErrorCode.Str(E121),
+				Solution:    SolutionMessage.Str(S112),
+				Code:        121,
 			}
-			return "", &encErr
-		} else {
-			encErr := EncapsulatedError{
-				OriginalErr: err,
-				Message:     ErrorCode.Str(E005),
-				Solution:    SolutionMessage.Str(S003, configFileRelativePath),
-				Code:        5,
+
+			if candidates := findConfigCandidates("."); len(candidates) > 0 {
+				encErr.Solution = fmt.Sprintf("%s. Found: %s", encErr.Solution, strings.Join(candidates, ", "))
 			}
-			return "", &encErr
+			return []string{}, &encErr
 		}
-	} else {
-		return configFileRelativePath, nil
+
+		configFileName, err := requestConfigFileFromUser(os.Stdin)
+		if err != nil {
+			return []string{}, err
+		}
+		configFiles = []string{configFileName}
 	}
-}
 
-func initializeJorgeProject(configFilePathFlag string) *EncapsulatedError {
 	jorgeDir, err := createJorgeDir()
 	if err != nil {
-		return err
+		return []string{}, err
 	}
 
-	var configFileName string
+	// The store may live outside of the project
+	projectRoot, err := resolveJorgeDir()
+	if err != nil {
+		return []string{}, err
+	}
 
-	if len(configFilePathFlag) > 0 {
-		configFileName = configFilePathFlag
-	} else {
-		configFileName, err = requestConfigFileFromUser()
+	store := jorgeDir
+	if len(store) == 0 {
+		if store, err = getJorgeDir(); err != nil {
+			return []string{}, err
+		}
 	}
 
+	// A failed init leaves the project as it was found
+	tx, err := beginTransaction(store, "init")
 	if err != nil {
-		return err
+		return []string{}, err
 	}
+	defer tx.close()
 
-	absConfigFileName, absConfigFileNameErr := filepath.Abs(configFileName)
+	if len(jorgeDir) > 0 {
+		err = tx.created(store)
+	} else {
+		err = tx.trackAll([]string{
+			filepath.Join(store, configFileName),
+			filepath.Join(store, secretRefsFileName),
+			filepath.Join(store, "envs"),
+		})
+	}
+	if err != nil {
+		return []string{}, err
+	}
 
-	if absConfigFileNameErr != nil {
-		encErr := EncapsulatedError{
-			OriginalErr: absConfigFileNameErr,
-			Message:     ErrorCode.Str(E008),
-			Solution:    SolutionMessage.Str(S003, absConfigFileName),
-			Code:        8,
+	trackedFiles := []string{}
+	trackedNames := []string{}
+	for _, configFileName := range configFiles {
+		absConfigFileName, absConfigFileNameErr := filepath.Abs(configFileName)
+
+		if absConfigFileNameErr != nil {
+			encErr := EncapsulatedError{
+				OriginalErr: absConfigFileNameErr,
+				Message:     ErrorCode.Str(E008),
+				Solution:    SolutionMessage.Str(S003, absConfigFileName),
+				Code:        8,
+			}
+
+			return []string{}, &encErr
 		}
 
-		return &encErr
-	}
+		if _, err := os.Stat(configFileName); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				encErr := EncapsulatedError{
+					OriginalErr: err,
+					Message:     ErrorCode.Str(E107),
+					Solution:    SolutionMessage.Str(S003, configFileName),
+					Code:        107,
+				}
+				return []string{}, &encErr
+			}
+		}
 
-	if _, err := os.Stat(configFileName); err != nil {
-		if errors.Is(err, os.ErrNotExist) {
+		var relativePathToConfig string
+		var relativePathToConfigErr error
+		if relativePathToConfig, relativePathToConfigErr = filepath.Rel(projectRoot, absConfigFileName); relativePathToConfigErr != nil {
 			encErr := EncapsulatedError{
-				OriginalErr: err,
+				OriginalErr: relativePathToConfigErr,
 				Message:     ErrorCode.Str(E107),
-				Solution:    SolutionMessage.Str(S003, configFileName),
+				Solution:    SolutionMessage.Str(S003, absConfigFileName),
 				Code:        107,
 			}
-			return &encErr
+			return []string{}, &encErr
 		}
-	}
 
-	var relativePathToConfig string
-	var relativePathToConfigErr error
-	if relativePathToConfig, relativePathToConfigErr = filepath.Rel(filepath.Join(jorgeDir, ".."), absConfigFileName); relativePathToConfigErr != nil {
-		encErr := EncapsulatedError{
-			OriginalErr: relativePathToConfigErr,
-			Message:     ErrorCode.Str(E107),
-			Solution:    SolutionMessage.Str(S003, absConfigFileName),
-			Code:        107,
+		_, trackedName := filepath.Split(relativePathToConfig)
+		if Contains(trackedNames, trackedName) {
+			if Contains(trackedFiles, relativePathToConfig) {
+				continue
+			}
+
+			encErr := EncapsulatedError{
+				OriginalErr: fmt.Errorf("%s is tracked twice", trackedName),
+				Message:     ErrorCode.Str(E122),
+				Solution:    SolutionMessage.Str(S113, trackedName),
+				Code:        122,
+			}
+			return []string{}, &encErr
 		}
-		return &encErr
+
+		trackedFiles = append(trackedFiles, relativePathToConfig)
+		trackedNames = append(trackedNames, trackedName)
 	}
 
 	freshJorgeConfig := JorgeConfig{
-		CurrentEnv:     "default",
-		ConfigFilePath: relativePathToConfig,
+		CurrentEnv:       "default",
+		ConfigFilePath:   trackedFiles[0],
+		ExtraConfigFiles: trackedFiles[1:],
+	}
+
+	// A store outside of the project records its root, since $JORGE_DIR does
+	// not say where the project is
+	if _, inProject := getStoreIgnorePath(projectRoot, store); !inProject {
+		freshJorgeConfig.Root = projectRoot
 	}
 
 	if _, err := setInternalConfig(freshJorgeConfig); err != nil {
-		return err
+		return []string{}, err
+	}
+
+	for _, trackedFile := range freshJorgeConfig.TrackedFiles() {
+		if _, storeFileErr := StoreConfigFile(filepath.Join(projectRoot, trackedFile), "default"); storeFileErr != nil {
+			return []string{}, storeFileErr
+		}
 	}
 
-	_, storeFileErr := StoreConfigFile(freshJorgeConfig.ConfigFilePath, "default")
+	if err := recordWorkingCopyBase(freshJorgeConfig, freshJorgeConfig.CurrentEnv, nil); err != nil {
+		log.Warn(fmt.Sprintf("%s: %s", err.Message, err.OriginalErr))
+	}
 
-	if storeFileErr != nil {
-		return storeFileErr
+	importedVariants, collisions, err := importConfigVariants(freshJorgeConfig, "", projectRoot, []string{freshJorgeConfig.CurrentEnv}, false)
+	if err != nil {
+		return []string{}, err
 	}
 
-	jorgeRecordExist, _ := ExistsInFile(".gitignore", ".jorge")
+	for _, collision := range collisions {
+		log.Warn(fmt.Sprintf("Skipping %s, the environment %s already exists", collision.Path, collision.EnvName))
+	}
 
-	if !jorgeRecordExist {
-		AppendToFile(".gitignore", ".jorge")
+	importedEnvs := []string{}
+	for _, variant := range importedVariants {
+		if !Contains(importedEnvs, variant.EnvName) {
+			importedEnvs = append(importedEnvs, variant.EnvName)
+		}
 	}
 
-	return nil
+	// A store outside of the project can not be committed by accident
+	if ignorePath, inProject := getStoreIgnorePath(projectRoot, jorgeDir); inProject {
+		gitignorePath := filepath.Join(projectRoot, ".gitignore")
+		jorgeRecordExist, _ := ExistsInFile(gitignorePath, ignorePath)
+
+		if !jorgeRecordExist {
+			tx.allow(gitignorePath)
+			if err := tx.track(gitignorePath); err != nil {
+				return []string{}, err
+			}
+			AppendToFile(gitignorePath, ignorePath)
+		}
+	}
+
+	tx.commit()
+	return importedEnvs, nil
 }
 
 func deleteJorgeEnv(env string) *EncapsulatedError {
 	jorgeDir, err := getJorgeDir()
 	if err != nil {
-		fmt.Println("Error")
-		return nil
+		return err
 	}
 
 	target := filepath.Join(jorgeDir, "envs", env)
@@ -586,7 +743,6 @@ func deleteJorgeEnv(env string) *EncapsulatedError {
 
 	removeErr := os.RemoveAll(target)
 	if removeErr != nil {
-		fmt.Println("could not remove ", target)
 		encError := EncapsulatedError{
 			OriginalErr: removeErr,
 			Message:     ErrorCode.Str(E009),
@@ -603,6 +759,16 @@ func deleteJorgeEnv(env string) *EncapsulatedError {
 // StoreConfigFile
 // It stores the current active user config file under an jorge environment name
 func StoreConfigFile(path string, envName string) (int64, *EncapsulatedError) {
+	_, fileName := filepath.Split(path)
+	return storeConfigFileAs(path, envName, fileName, true)
+}
+
+// storeConfigFileAs
+// It stores the file found at path under an jorge environment, using fileName
+// as the name of the stored file. The attributes of the file are recorded
+// unless recordAttributes is false, which keeps the ones already recorded for
+// files stored from a temporary copy
+func storeConfigFileAs(path string, envName string, fileName string, recordAttributes bool) (int64, *EncapsulatedError) {
 	envsDir, err := getEnvsDirPath()
 
 	if err != nil {
@@ -634,6 +800,7 @@ func StoreConfigFile(path string, envName string) (int64, *EncapsulatedError) {
 	}
 
 	targetEnvDirName := filepath.Join(envsDir, envName)
+	createdEnv := false
 	if _, err := os.Stat(targetEnvDirName); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			mkdirErr := os.Mkdir(targetEnvDirName, 0700)
@@ -647,6 +814,7 @@ func StoreConfigFile(path string, envName string) (int64, *EncapsulatedError) {
 				return -3, &encErr
 			}
 			log.Debug(fmt.Sprintf("Created env dir %s", targetEnvDirName))
+			createdEnv = true
 		} else {
 			encError := EncapsulatedError{
 				OriginalErr: err,
@@ -658,10 +826,19 @@ func StoreConfigFile(path string, envName string) (int64, *EncapsulatedError) {
 		}
 	}
 
+	destinationPath := filepath.Join(targetEnvDirName, fileName)
+
+	// In symlink mode the working file is the stored file, so there is
+	// nothing to store
+	if isLinkedTo(path, destinationPath) {
+		log.Debug(fmt.Sprintf("%s is linked to %s, nothing to store", path, destinationPath))
+		return activeConfigFileMeta.Size(), nil
+	}
+
+	// The source is read before the destination is truncated, since the
+	// source may be a link to it
 	log.Debug(fmt.Sprintf("Source path is %v", path))
-	sourceFile, sourceFileErr := os.Open(path)
-	log.Debug("Source file opened")
-	defer sourceFile.Close()
+	workingData, sourceFileErr := ioutil.ReadFile(path)
 
 	if sourceFileErr != nil {
 		encErr := EncapsulatedError{
@@ -674,10 +851,7 @@ func StoreConfigFile(path string, envName string) (int64, *EncapsulatedError) {
 		return -1, &encErr
 	}
 
-	_, fileName := filepath.Split(path)
-
-	destinationPath := filepath.Join(targetEnvDirName, fileName)
-	destination, destinationErr := os.Create(destinationPath)
+	destination, destinationErr := os.OpenFile(destinationPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, privateFileMode)
 
 	if destinationErr != nil {
 		encErr := EncapsulatedError{
@@ -693,7 +867,12 @@ func StoreConfigFile(path string, envName string) (int64, *EncapsulatedError) {
 	log.Debug("Destination file created")
 	defer destination.Close()
 
-	nBytes, copyErr := io.Copy(destination, sourceFile)
+	written, copyErr := destination.Write(unrenderConfigFile(fileName, workingData))
+	nBytes := int64(written)
+	if copyErr == nil {
+		// Files stored by older versions were readable by everyone
+		copyErr = destination.Chmod(privateFileMode)
+	}
 	log.Debug(fmt.Sprintf("Wrote %d bytes from %v to %v", nBytes, path, destinationPath))
 
 	if copyErr != nil {
@@ -707,12 +886,22 @@ func StoreConfigFile(path string, envName string) (int64, *EncapsulatedError) {
 		return -1, &encErr
 	}
 
+	if err := touchEnvMeta(envName, createdEnv); err != nil {
+		log.Warn(fmt.Sprintf("%s: %s", err.Message, err.OriginalErr))
+	}
+
+	if recordAttributes {
+		if err := recordFileAttributes(envName, fileName, activeConfigFileMeta); err != nil {
+			log.Warn(fmt.Sprintf("%s: %s", err.Message, err.OriginalErr))
+		}
+	}
+
 	return nBytes, nil
 }
 
 // UseConfigFile
 // It replaces the current active user configuration file with the one that is
-// stored under the jorge environment
+// stored under the jorge environment and returns the number of bytes written
 func UseConfigFile(envName string, createEnv bool) (int64, *EncapsulatedError) {
 
 	config, err := getInternalConfig()
@@ -721,7 +910,39 @@ func UseConfigFile(envName string, createEnv bool) (int64, *EncapsulatedError) {
 		return -1, err
 	}
 
-	target := config.ConfigFilePath
+	if err := runHooks(config, PreUse, config.CurrentEnv, envName); err != nil {
+		return -1, err
+	}
+
+	// The name is checked before the transaction tracks the directory of the
+	// environment
+	if err := validateEnvName(envName); err != nil {
+		return -1, err
+	}
+
+	if !createEnv {
+		if _, err := getEnvDirPath(envName); err != nil {
+			return -1, err
+		}
+	}
+
+	location, err := locateProject()
+	if err != nil {
+		return -1, err
+	}
+	jorgeDir := location.Root
+
+	// The working files and the internal configuration are kept in step, the
+	// switch is undone when any of them can not be changed
+	tx, err := beginTransaction(location.Store, "use")
+	if err != nil {
+		return -1, err
+	}
+	defer tx.close()
+
+	if err := tx.trackEnvSwitch(config, location, config.CurrentEnv, envName); err != nil {
+		return -1, err
+	}
 
 	if createEnv {
 		if existingEnvs, err := getEnvs(); err == nil {
@@ -733,11 +954,18 @@ func UseConfigFile(envName string, createEnv bool) (int64, *EncapsulatedError) {
 					Code:        109,
 				}
 				return -1, &encErr
+			} else if err := validateWorkingFiles(config, envName); err != nil {
+				return -1, err
 			} else {
-				if _, err := StoreConfigFile(target, envName); err != nil {
-					return -1, err
-				} else {
-					log.Debug(fmt.Sprintf("Created new file for env %s", envName))
+				for _, target := range config.TrackedFiles() {
+					if _, err := StoreConfigFile(filepath.Join(jorgeDir, target), envName); err != nil {
+						return -1, err
+					}
+				}
+				log.Debug(fmt.Sprintf("Created new file for env %s", envName))
+
+				if err := setEnvParent(envName, config.CurrentEnv); err != nil {
+					log.Warn(fmt.Sprintf("%s: %s", err.Message, err.OriginalErr))
 				}
 			}
 		} else {
@@ -745,30 +973,50 @@ func UseConfigFile(envName string, createEnv bool) (int64, *EncapsulatedError) {
 		}
 	}
 
-	jorgeDir, encErr := resolveJorgeDir()
-	if encErr != nil {
-		fmt.Println(encErr)
-	} else {
-		fmt.Println("hello: ")
-		fmt.Print(jorgeDir)
-	}
-
-	resolvedTarget := filepath.Join(jorgeDir, target)
-	if _, err := setConfigAsMain(resolvedTarget, envName); err != nil {
+	if err := validateStoredFiles(config, envName); err != nil {
 		return -1, err
-	} else {
-		log.Debug(fmt.Sprintf("Used %s as main config file", envName))
-
-		newConfig := JorgeConfig{
-			CurrentEnv: envName,
-		}
+	}
 
-		if _, err := setInternalConfig(newConfig); err != nil {
+	var written int64
+	for _, target := range config.TrackedFiles() {
+		resolvedTarget := filepath.Join(jorgeDir, target)
+		if nBytes, err := setConfigAsMain(resolvedTarget, envName); err != nil {
 			return -1, err
 		} else {
-			return 1, nil
+			written += nBytes
 		}
 	}
+	log.Debug(fmt.Sprintf("Used %s as main config file", envName))
+
+	if envName != config.CurrentEnv {
+		removeWorkingCopyBase(config.CurrentEnv)
+	}
+	if err := recordWorkingCopyBase(config, envName, nil); err != nil {
+		log.Warn(fmt.Sprintf("%s: %s", err.Message, err.OriginalErr))
+	}
+
+	newConfig := JorgeConfig{
+		CurrentEnv: envName,
+	}
+
+	if _, err := setInternalConfig(newConfig); err != nil {
+		return -1, err
+	} else {
+		tx.commit()
+		runPostHooks(config, PostUse, config.CurrentEnv, envName)
+		return written, nil
+	}
+}
+
+// GetCurrentEnv
+// Returns the name of the environment that the project uses
+func GetCurrentEnv() (string, *EncapsulatedError) {
+	config, err := getInternalConfig()
+	if err != nil {
+		return "", err
+	}
+
+	return config.CurrentEnv, nil
 }
 
 func SelectEnvironment(envName string) *EncapsulatedError {
@@ -777,51 +1025,166 @@ func SelectEnvironment(envName string) *EncapsulatedError {
 	}); err != nil {
 		return err
 	} else {
-		fmt.Println(fmt.Sprintf("Using environment %s", envName))
+		log.Debug(fmt.Sprintf("Using environment %s", envName))
 		return nil
 	}
 }
 
+// EnvInfo
+// Describes an environment as it is shown by `jorge ls`
+type EnvInfo struct {
+	Name       string    `json:"name" yaml:"name"`
+	Current    bool      `json:"current" yaml:"current"`
+	Committed  bool      `json:"committed" yaml:"committed"`
+	Dirty      bool      `json:"dirty" yaml:"dirty"`
+	Expired    bool      `json:"expired" yaml:"expired"`
+	Size       int64     `json:"size" yaml:"size"`
+	Modified   time.Time `json:"modified" yaml:"modified"`
+	LastCommit time.Time `json:"lastCommit" yaml:"lastCommit"`
+	Meta       EnvMeta   `json:"meta" yaml:"meta"`
+}
+
+// isWorkingCopyDirty
+// Determines whether any of the active user configuration files differs from
+// the version that is stored for the current environment
+func isWorkingCopyDirty(config JorgeConfig, envDir string) bool {
+	jorgeDir, err := resolveJorgeDir()
+	if err != nil {
+		return false
+	}
+
+	for _, trackedFile := range config.TrackedFiles() {
+		_, trackedFileName := filepath.Split(trackedFile)
+		workingData, workingErr := ioutil.ReadFile(filepath.Join(jorgeDir, trackedFile))
+		storedData, storedErr := ioutil.ReadFile(filepath.Join(envDir, trackedFileName))
+
+		if workingErr != nil || storedErr != nil {
+			if workingErr == nil || storedErr == nil {
+				return true
+			}
+		} else if !bytes.Equal(unrenderConfigFile(trackedFileName, workingData), storedData) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ListEnvironments
-// Shows a list with all the available environment for the user
-func ListEnvironments() *EncapsulatedError {
+// Returns the available environments of the user. A non empty tag returns only
+// the environments that are labeled with it. The current environment is
+// returned as uncommitted when it has not been stored yet
+func ListEnvironments(tag string) ([]EnvInfo, *EncapsulatedError) {
 	envs, err := getEnvs()
 
 	if err != nil {
-		return err
+		return []EnvInfo{}, err
 	}
 
 	config, err := getInternalConfig()
 	if err != nil {
-		return err
+		return []EnvInfo{}, err
+	}
+
+	envsDir, err := getEnvsDirPath()
+	if err != nil {
+		return []EnvInfo{}, err
 	}
 
+	_, trackedFileName := filepath.Split(config.ConfigFilePath)
+
+	infos := []EnvInfo{}
 	currentEnvFound := false
 	for _, fileName := range envs {
+		meta, err := getEnvMeta(fileName)
+		if err != nil {
+			return []EnvInfo{}, err
+		}
+
 		if fileName == config.CurrentEnv {
 			currentEnvFound = true
-			fmt.Printf("* %s\n", fileName)
+		}
+
+		if len(tag) > 0 && !meta.HasTag(tag) {
+			continue
+		}
+
+		info := EnvInfo{
+			Name:       fileName,
+			Current:    fileName == config.CurrentEnv,
+			Committed:  true,
+			Expired:    meta.IsExpired(),
+			LastCommit: meta.Updated,
+			Meta:       meta,
+		}
+
+		storedFilePath := filepath.Join(envsDir, fileName, trackedFileName)
+		if storedFile, statErr := os.Stat(storedFilePath); statErr == nil {
+			info.Size = storedFile.Size()
+			info.Modified = storedFile.ModTime()
+
+			if info.LastCommit.IsZero() {
+				info.LastCommit = info.Modified
+			}
 		} else {
-			fmt.Println(fileName)
+			log.Debug(fmt.Sprintf("Env %s has no stored file %s", fileName, trackedFileName))
+		}
+
+		if info.Current {
+			info.Dirty = isWorkingCopyDirty(config, filepath.Join(envsDir, fileName))
 		}
+
+		infos = append(infos, info)
 	}
 
-	if !currentEnvFound {
-		fmt.Printf("* %s (uncommitted)\n", config.CurrentEnv)
+	if !currentEnvFound && len(tag) == 0 {
+		infos = append(infos, EnvInfo{
+			Name:    config.CurrentEnv,
+			Current: true,
+			Dirty:   true,
+		})
 	}
 
+	return infos, nil
+}
+
+// SortEnvironments
+// Sorts the environments by name, by the modification time of their stored file
+// (newest first) or by the size of their stored file (largest first)
+func SortEnvironments(infos []EnvInfo, key string) *EncapsulatedError {
+	var less func(i, j int) bool
+
+	switch key {
+	case "", "name":
+		less = func(i, j int) bool { return infos[i].Name < infos[j].Name }
+	case "modified":
+		less = func(i, j int) bool { return infos[i].Modified.After(infos[j].Modified) }
+	case "size":
+		less = func(i, j int) bool { return infos[i].Size > infos[j].Size }
+	default:
+		encErr := EncapsulatedError{
+			OriginalErr: fmt.Errorf("unknown sort key %s", key),
+			Message:     ErrorCode.Str(E118),
+			Solution:    SolutionMessage.Str(S109, key, "name, modified, size"),
+			Code:        118,
+		}
+		return &encErr
+	}
+
+	sort.SliceStable(infos, less)
 	return nil
 }
 
 // Init
 // Initializes a jorge project by creating the .jorge directory, setting the
-// first environment and requesting the config file path for the user
-func Init(configFilePathFlag string) *EncapsulatedError {
-	if err := initializeJorgeProject(configFilePathFlag); err != nil {
-		removeJorgeDir()
-		return err
+// first environment and requesting the config file path for the user when
+// none is given and the session is interactive. The variants of the config
+// files are imported as environments and their names are returned
+func Init(configFiles []string, interactive bool) ([]string, *EncapsulatedError) {
+	if importedEnvs, err := initializeJorgeProject(configFiles, interactive); err != nil {
+		return []string{}, err
 	} else {
-		return nil
+		return importedEnvs, nil
 	}
 }
 
@@ -840,14 +1203,51 @@ func CommitCurrentEnv() *EncapsulatedError {
 		return err
 	}
 
-	activeUserConfig := filepath.Join(jorgeDir, config.ConfigFilePath)
-	_, storeConfigErr := StoreConfigFile(activeUserConfig, config.CurrentEnv)
+	if err := runHooks(config, PreCommit, config.CurrentEnv, config.CurrentEnv); err != nil {
+		return err
+	}
+
+	location, err := locateProject()
+	if err != nil {
+		return err
+	}
 
-	if storeConfigErr != nil {
-		return storeConfigErr
-	} else {
-		return nil
+	tx, err := beginTransaction(location.Store, "commit")
+	if err != nil {
+		return err
 	}
+	defer tx.close()
+
+	if err := tx.trackEnvSwitch(config, location, config.CurrentEnv, config.CurrentEnv); err != nil {
+		return err
+	}
+
+	if err := mergeStoredChanges(config, jorgeDir); err != nil {
+		// The conflicts are written to the working files to be resolved
+		if ErrorCode(E137).Is(ErrorCode(err.Message)) {
+			tx.commit()
+		}
+		return err
+	}
+
+	if err := validateWorkingFiles(config, config.CurrentEnv); err != nil {
+		return err
+	}
+
+	for _, trackedFile := range config.TrackedFiles() {
+		activeUserConfig := filepath.Join(jorgeDir, trackedFile)
+		if _, storeConfigErr := StoreConfigFile(activeUserConfig, config.CurrentEnv); storeConfigErr != nil {
+			return storeConfigErr
+		}
+	}
+
+	if err := recordWorkingCopyBase(config, config.CurrentEnv, nil); err != nil {
+		log.Warn(fmt.Sprintf("%s: %s", err.Message, err.OriginalErr))
+	}
+
+	tx.commit()
+	runPostHooks(config, PostCommit, config.CurrentEnv, config.CurrentEnv)
+	return nil
 }
 
 // RestoreEnv
@@ -865,14 +1265,23 @@ func RestoreEnv() *EncapsulatedError {
 		return err
 	}
 
-	activeUserConfig := filepath.Join(jorgeDir, config.ConfigFilePath)
-	_, restoreError := setConfigAsMain(activeUserConfig, config.CurrentEnv)
+	if err := runHooks(config, PreRestore, config.CurrentEnv, config.CurrentEnv); err != nil {
+		return err
+	}
 
-	if restoreError != nil {
-		return restoreError
-	} else {
-		return nil
+	for _, trackedFile := range config.TrackedFiles() {
+		activeUserConfig := filepath.Join(jorgeDir, trackedFile)
+		if _, restoreError := setConfigAsMain(activeUserConfig, config.CurrentEnv); restoreError != nil {
+			return restoreError
+		}
+	}
+
+	if err := recordWorkingCopyBase(config, config.CurrentEnv, nil); err != nil {
+		log.Warn(fmt.Sprintf("%s: %s", err.Message, err.OriginalErr))
 	}
+
+	runPostHooks(config, PostRestore, config.CurrentEnv, config.CurrentEnv)
+	return nil
 }
 
 func RemoveEnv(envName string) *EncapsulatedError {
@@ -915,9 +1324,29 @@ func RemoveEnv(envName string) *EncapsulatedError {
 		return &encErr
 	}
 
+	jorgeDir, err := getJorgeDir()
+	if err != nil {
+		return err
+	}
+
+	if err := takeAutomaticSnapshot(jorgeDir, config.Snapshots, "rm-"+envName); err != nil {
+		return err
+	}
+
+	tx, err := beginTransaction(jorgeDir, "rm")
+	if err != nil {
+		return err
+	}
+	defer tx.close()
+
+	if err := tx.track(filepath.Join(jorgeDir, "envs", envName)); err != nil {
+		return err
+	}
+
 	if err = deleteJorgeEnv(envName); err != nil {
 		return err
 	}
 
+	tx.commit()
 	return nil
 }
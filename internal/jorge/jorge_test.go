@@ -167,7 +167,7 @@ func TestGetInternalConfig(t *testing.T) {
 	os.Mkdir(filepath.Join(testingRoot, ".jorge"), 0700)
 	defer os.Remove(filepath.Join(testingRoot, ".jorge"))
 
-	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("currentEnv: default\nconfigFilePath: .env"), 0600); err != nil {
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: .env"), 0600); err != nil {
 		t.Fail()
 	}
 	defer os.Remove(filepath.Join(testingRoot, ".jorge", "config.yml"))
@@ -318,7 +318,7 @@ func TestUseConfigFileWhenEnvExists(t *testing.T) {
 	os.Mkdir(filepath.Join(testingRoot, ".jorge"), 0700)
 	defer os.Remove(filepath.Join(testingRoot, ".jorge"))
 
-	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("currentEnv: default\nconfigFilePath: mainTestConfig"), 0600); err != nil {
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: mainTestConfig"), 0600); err != nil {
 		t.Fail()
 	}
 	defer os.Remove(filepath.Join(testingRoot, ".jorge", "config.yml"))
@@ -368,7 +368,7 @@ func TestUseConfigFileWhenEnvDoesNotExist(t *testing.T) {
 	os.Mkdir(filepath.Join(testingRoot, ".jorge"), 0700)
 	defer os.Remove(filepath.Join(testingRoot, ".jorge"))
 
-	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("currentEnv: default\nconfigFilePath: mainTestConfig"), 0600); err != nil {
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: default\nconfigFilePath: mainTestConfig"), 0600); err != nil {
 		t.Fail()
 	}
 	defer os.Remove(filepath.Join(testingRoot, ".jorge", "config.yml"))
@@ -425,7 +425,7 @@ func TestCommitCurrentEnv(t *testing.T) {
 	}
 	defer os.Remove(filepath.Join(testingRoot, "mainTestConfig"))
 
-	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("currentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
 		t.FailNow()
 	}
 	defer os.Remove(filepath.Join(testingRoot, ".jorge", "config.yml"))
@@ -468,7 +468,7 @@ func TestCommitCurrentEnvWithAbsPath(t *testing.T) {
 	}
 	defer os.Remove(filepath.Join(testingRoot, "mainTestConfig"))
 
-	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("currentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
 		t.FailNow()
 	}
 	defer os.Remove(filepath.Join(testingRoot, ".jorge", "config.yml"))
@@ -519,7 +519,7 @@ func TestCommitCurrentEnvWithNestedConfigFile(t *testing.T) {
 	}
 	defer os.Remove(filepath.Join(testingRoot, "level01", "level02", "mainTestConfig"))
 
-	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("currentEnv: mockEnv\nconfigFilePath: level01/level02/mainTestConfig"), 0600); err != nil {
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: level01/level02/mainTestConfig"), 0600); err != nil {
 		t.FailNow()
 	}
 	defer os.Remove(filepath.Join(testingRoot, ".jorge", "config.yml"))
@@ -563,7 +563,7 @@ func TestRestoreEnv(t *testing.T) {
 	}
 	defer os.Remove(filepath.Join(testingRoot, "mainTestConfig"))
 
-	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("currentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
 		t.FailNow()
 	}
 	defer os.Remove(filepath.Join(testingRoot, ".jorge", "config.yml"))
@@ -606,7 +606,7 @@ func TestRestoreEnvWithAbsPath(t *testing.T) {
 	}
 	defer os.Remove(filepath.Join(testingRoot, "mainTestConfig"))
 
-	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("currentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
 		t.FailNow()
 	}
 	defer os.Remove(filepath.Join(testingRoot, ".jorge", "config.yml"))
@@ -657,7 +657,7 @@ func TestRestoreEnvWithNestedConfigFile(t *testing.T) {
 	}
 	defer os.Remove(filepath.Join(testingRoot, "mainTestConfig"))
 
-	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("currentEnv: mockEnv\nconfigFilePath: level01/level02/mainTestConfig"), 0600); err != nil {
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: level01/level02/mainTestConfig"), 0600); err != nil {
 		t.FailNow()
 	}
 	defer os.Remove(filepath.Join(testingRoot, ".jorge", "config.yml"))
@@ -676,3 +676,56 @@ func TestRestoreEnvWithNestedConfigFile(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestListEnvironments(t *testing.T) {
+	testingRoot := filepath.Join(os.TempDir(), "jorge-testing")
+	os.Mkdir(testingRoot, 0700)
+	defer os.Remove(testingRoot)
+	os.Chdir(testingRoot)
+
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv"), 0700)
+	os.MkdirAll(filepath.Join(testingRoot, ".jorge", "envs", "otherMockEnv"), 0700)
+	defer os.RemoveAll(filepath.Join(testingRoot, ".jorge"))
+
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "mockEnv", "mainTestConfig"), []byte("mock config contents"), 0600)
+	os.WriteFile(filepath.Join(testingRoot, ".jorge", "envs", "otherMockEnv", "mainTestConfig"), []byte("other mock config contents"), 0600)
+
+	if err := os.WriteFile(filepath.Join(testingRoot, "mainTestConfig"), []byte("updated mock config contents"), 0600); err != nil {
+		t.FailNow()
+	}
+	defer os.Remove(filepath.Join(testingRoot, "mainTestConfig"))
+
+	if err := os.WriteFile(filepath.Join(testingRoot, ".jorge", "config.yml"), []byte("version: 1\ncurrentEnv: mockEnv\nconfigFilePath: mainTestConfig"), 0600); err != nil {
+		t.FailNow()
+	}
+
+	envs, err := ListEnvironments("")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(envs) != 2 {
+		t.Fatalf("Expected 2 environments, but found %d", len(envs))
+	}
+
+	if err := SortEnvironments(envs, "size"); err != nil {
+		t.Fatal(err)
+	}
+
+	if envs[0].Name != "otherMockEnv" || envs[0].Size != int64(len("other mock config contents")) {
+		t.Fatalf("Environments were not sorted by size: %v", envs)
+	} else if envs[0].Current || envs[0].Dirty {
+		t.Fatal("otherMockEnv is marked as current")
+	} else if !envs[1].Current || !envs[1].Dirty {
+		t.Fatal("mockEnv is not marked as current and dirty")
+	}
+
+	if err := SortEnvironments(envs, "unknown"); err == nil {
+		t.Fatal("Sorted by an unknown key")
+	}
+
+	AddEnvTag("otherMockEnv", "mockTag")
+	if envs, err := ListEnvironments("mockTag"); err != nil {
+		t.Fatal(err)
+	} else if len(envs) != 1 || envs[0].Name != "otherMockEnv" {
+		t.Fatalf("Expected only otherMockEnv, but found %v", envs)
+	}
+}